@@ -0,0 +1,419 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nosefu/go-tg"
+)
+
+// AccessSubject identifies user, chat or username in access lists.
+// Use [AccessUser], [AccessChat], [AccessUsername] or [ParseAccessSubject] to create it.
+type AccessSubject string
+
+// AccessUser returns subject of the user with specified id.
+func AccessUser(id tg.UserID) AccessSubject {
+	return AccessSubject("user:" + strconv.FormatInt(int64(id), 10))
+}
+
+// AccessChat returns subject of the chat with specified id.
+func AccessChat(id tg.ChatID) AccessSubject {
+	return AccessSubject("chat:" + strconv.FormatInt(int64(id), 10))
+}
+
+// AccessUsername returns subject of the user or chat with specified username.
+// Username is case insensitive, @ prefix is optional.
+func AccessUsername(username string) AccessSubject {
+	return AccessSubject("username:" + strings.ToLower(strings.TrimPrefix(username, "@")))
+}
+
+// ParseAccessSubject parses subject from string.
+// Following formats are supported:
+//   - user:123, chat:-100123, username:name — as returned by [AccessSubject.String];
+//   - @name — username;
+//   - 123 — user id, if positive, or chat id, if negative.
+func ParseAccessSubject(v string) (AccessSubject, error) {
+	kind, value, ok := strings.Cut(v, ":")
+	if !ok {
+		value = v
+
+		switch {
+		case strings.HasPrefix(v, "@"):
+			kind = "username"
+		case strings.HasPrefix(v, "-"):
+			kind = "chat"
+		default:
+			kind = "user"
+		}
+	}
+
+	switch kind {
+	case "user", "chat":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id == 0 {
+			return "", fmt.Errorf("invalid %s id: %q", kind, value)
+		}
+
+		if kind == "user" {
+			return AccessUser(tg.UserID(id)), nil
+		}
+
+		return AccessChat(tg.ChatID(id)), nil
+	case "username":
+		username := strings.TrimPrefix(value, "@")
+		if username == "" {
+			return "", fmt.Errorf("empty username")
+		}
+
+		return AccessUsername(username), nil
+	default:
+		return "", fmt.Errorf("unknown access subject kind: %q", kind)
+	}
+}
+
+// String returns subject in format user:123, chat:-100123 or username:name.
+func (subject AccessSubject) String() string {
+	return string(subject)
+}
+
+// AccessRule is a rule of subject in access lists.
+type AccessRule int
+
+const (
+	// AccessRuleNone means subject is not listed.
+	AccessRuleNone AccessRule = iota
+	// AccessRuleAllow means subject is in allow list.
+	AccessRuleAllow
+	// AccessRuleDeny means subject is in deny list.
+	AccessRuleDeny
+)
+
+// String returns name of the rule.
+func (rule AccessRule) String() string {
+	switch rule {
+	case AccessRuleAllow:
+		return "allow"
+	case AccessRuleDeny:
+		return "deny"
+	default:
+		return "none"
+	}
+}
+
+// AccessEntry is an entry of access lists.
+type AccessEntry struct {
+	Subject AccessSubject
+	Rule    AccessRule
+}
+
+// AccessStore define interface for storage of access lists.
+// Implement it to load lists from database or config, see [AccessStoreMemory] for example.
+type AccessStore interface {
+	// Get returns rule of subject. Not listed subject has AccessRuleNone.
+	Get(ctx context.Context, subject AccessSubject) (AccessRule, error)
+
+	// Set sets rule of subject. AccessRuleNone removes subject from lists.
+	Set(ctx context.Context, subject AccessSubject, rule AccessRule) error
+
+	// List returns all entries of lists.
+	List(ctx context.Context) ([]AccessEntry, error)
+}
+
+// AccessStoreMemory is an in-memory store of access lists.
+// It implements [AccessStore] and is thread-safe.
+type AccessStoreMemory struct {
+	lock  sync.RWMutex
+	rules map[AccessSubject]AccessRule
+}
+
+var _ AccessStore = (*AccessStoreMemory)(nil)
+
+// NewAccessStoreMemory creates a new AccessStoreMemory with static entries.
+func NewAccessStoreMemory(entries ...AccessEntry) *AccessStoreMemory {
+	store := &AccessStoreMemory{}
+	store.Replace(entries)
+	return store
+}
+
+// Replace replaces all entries of lists.
+// It can be used to reload lists periodically from external source.
+func (store *AccessStoreMemory) Replace(entries []AccessEntry) {
+	rules := make(map[AccessSubject]AccessRule, len(entries))
+
+	for _, entry := range entries {
+		if entry.Rule != AccessRuleNone {
+			rules[entry.Subject] = entry.Rule
+		}
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.rules = rules
+}
+
+func (store *AccessStoreMemory) Get(ctx context.Context, subject AccessSubject) (AccessRule, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	return store.rules[subject], nil
+}
+
+func (store *AccessStoreMemory) Set(ctx context.Context, subject AccessSubject, rule AccessRule) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if rule == AccessRuleNone {
+		delete(store.rules, subject)
+	} else {
+		store.rules[subject] = rule
+	}
+
+	return nil
+}
+
+func (store *AccessStoreMemory) List(ctx context.Context) ([]AccessEntry, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	entries := make([]AccessEntry, 0, len(store.rules))
+	for subject, rule := range store.rules {
+		entries = append(entries, AccessEntry{Subject: subject, Rule: rule})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Subject < entries[j].Subject
+	})
+
+	return entries, nil
+}
+
+// AccessControl checks access of updates by allow and deny lists.
+//
+// Update is checked by user, sender chat and chat of the update (ids and usernames):
+//   - if any of them is denied, update is denied;
+//   - if any of them is allowed, update is allowed;
+//   - otherwise default policy is applied, by default not listed updates are denied.
+//
+// Updates without user and chat (e.g. poll) are always allowed.
+type AccessControl struct {
+	store        AccessStore
+	defaultAllow bool
+	reply        string
+}
+
+// AccessControlOption is an option for [NewAccessControl].
+type AccessControlOption func(*AccessControl)
+
+// WithAccessControlDefaultAllow sets policy for not listed subjects.
+// If true, only subjects from deny list are denied.
+// By default is false, so only subjects from allow list are allowed.
+func WithAccessControlDefaultAllow(allow bool) AccessControlOption {
+	return func(ac *AccessControl) {
+		ac.defaultAllow = allow
+	}
+}
+
+// WithAccessControlReply sets text which is sent by [AccessControl.Guard] on denied messages and callback queries.
+// By default denied updates are dropped silently.
+func WithAccessControlReply(text string) AccessControlOption {
+	return func(ac *AccessControl) {
+		ac.reply = text
+	}
+}
+
+// NewAccessControl creates a new AccessControl with specified store.
+func NewAccessControl(store AccessStore, opts ...AccessControlOption) *AccessControl {
+	ac := &AccessControl{
+		store: store,
+	}
+
+	for _, opt := range opts {
+		opt(ac)
+	}
+
+	return ac
+}
+
+// getUpdateAccessSubjects returns subjects of user, sender chat and chat of the update.
+func getUpdateAccessSubjects(update *Update) []AccessSubject {
+	var subjects []AccessSubject
+
+	if user := getUpdateUser(update); user != nil {
+		subjects = append(subjects, AccessUser(user.ID))
+		if user.Username != "" {
+			subjects = append(subjects, AccessUsername(string(user.Username)))
+		}
+	}
+
+	chats := []*tg.Chat{update.Chat()}
+	if msg := getUpdateAnyMessage(update); msg != nil {
+		chats = append(chats, msg.SenderChat)
+	}
+
+	for _, chat := range chats {
+		if chat == nil {
+			continue
+		}
+
+		subjects = append(subjects, AccessChat(chat.ID))
+		if chat.Username != "" {
+			subjects = append(subjects, AccessUsername(string(chat.Username)))
+		}
+	}
+
+	return subjects
+}
+
+// Check checks if update is allowed.
+func (ac *AccessControl) Check(ctx context.Context, update *Update) (bool, error) {
+	subjects := getUpdateAccessSubjects(update)
+	if len(subjects) == 0 {
+		return true, nil
+	}
+
+	allowed := false
+
+	for _, subject := range subjects {
+		rule, err := ac.store.Get(ctx, subject)
+		if err != nil {
+			return false, fmt.Errorf("get access rule of %s: %w", subject, err)
+		}
+
+		switch rule {
+		case AccessRuleDeny:
+			return false, nil
+		case AccessRuleAllow:
+			allowed = true
+		}
+	}
+
+	return allowed || ac.defaultAllow, nil
+}
+
+// Allowed returns a filter which allows only updates allowed by access control.
+// Use [Not] to handle denied updates.
+func (ac *AccessControl) Allowed() Filter {
+	return FilterFunc(ac.Check)
+}
+
+// Guard is a [GlobalMiddlewareFunc] which drops denied updates.
+// If reply is set (see [WithAccessControlReply]), it's sent on denied messages and callback queries.
+func (ac *AccessControl) Guard(ctx context.Context, update *Update) (context.Context, *Update, error) {
+	allowed, err := ac.Check(ctx, update)
+	if err != nil {
+		return ctx, update, fmt.Errorf("access control: %w", err)
+	}
+
+	if allowed {
+		return ctx, update, nil
+	}
+
+	if ac.reply != "" {
+		if err := ac.sendReply(ctx, update); err != nil {
+			return ctx, update, fmt.Errorf("access control reply: %w", err)
+		}
+	}
+
+	return ctx, update, ErrDropUpdate
+}
+
+func (ac *AccessControl) sendReply(ctx context.Context, update *Update) error {
+	switch {
+	case update.CallbackQuery != nil:
+		return update.Reply(ctx, update.Client.AnswerCallbackQuery(update.CallbackQuery.ID).Text(ac.reply))
+	case update.Message != nil:
+		return update.Reply(ctx, update.Client.SendMessage(update.Message.Chat, ac.reply))
+	default:
+		return nil
+	}
+}
+
+type accessCommandArgs struct {
+	Subject  string   `cmd:"subject"`
+	Subjects []string `cmd:"subjects,rest"`
+}
+
+// RegisterCommands registers commands to manage access lists at runtime:
+//   - /access_allow <subject> [subjects...] — adds subjects to allow list;
+//   - /access_deny <subject> [subjects...] — adds subjects to deny list;
+//   - /access_remove <subject> [subjects...] — removes subjects from lists;
+//   - /access_list — lists all entries.
+//
+// Subjects are parsed with [ParseAccessSubject].
+// Admin filter is required and should restrict commands to bot administrators,
+// e.g. by allow list of separate AccessControl. Use [All] to combine multiple filters.
+// It panics if admin filter is nil, otherwise anyone could manage access lists.
+func (ac *AccessControl) RegisterCommands(router *Router, admin Filter) *Router {
+	if admin == nil {
+		panic("tgb: AccessControl.RegisterCommands: admin filter is required")
+	}
+
+	withCommand := func(name string) []Filter {
+		return []Filter{admin, Command(name)}
+	}
+
+	for _, cmd := range []struct {
+		name   string
+		rule   AccessRule
+		result string
+	}{
+		{"access_allow", AccessRuleAllow, "allowed"},
+		{"access_deny", AccessRuleDeny, "denied"},
+		{"access_remove", AccessRuleNone, "removed"},
+	} {
+		cmd := cmd
+
+		router.Message(CommandHandler(func(ctx context.Context, msg *MessageUpdate, args accessCommandArgs) error {
+			subjects := make([]AccessSubject, 0, len(args.Subjects)+1)
+
+			for _, v := range append([]string{args.Subject}, args.Subjects...) {
+				subject, err := ParseAccessSubject(v)
+				if err != nil {
+					return msg.Answer(err.Error()).DoVoid(ctx)
+				}
+
+				subjects = append(subjects, subject)
+			}
+
+			for _, subject := range subjects {
+				if err := ac.store.Set(ctx, subject, cmd.rule); err != nil {
+					return fmt.Errorf("set access rule of %s: %w", subject, err)
+				}
+			}
+
+			return msg.Answer(fmt.Sprintf("%s: %s", cmd.result, joinAccessSubjects(subjects))).DoVoid(ctx)
+		}), withCommand(cmd.name)...)
+	}
+
+	return router.Message(func(ctx context.Context, msg *MessageUpdate) error {
+		entries, err := ac.store.List(ctx)
+		if err != nil {
+			return fmt.Errorf("list access entries: %w", err)
+		}
+
+		if len(entries) == 0 {
+			return msg.Answer("access lists are empty").DoVoid(ctx)
+		}
+
+		lines := make([]string, len(entries))
+		for i, entry := range entries {
+			lines[i] = entry.Rule.String() + " " + entry.Subject.String()
+		}
+
+		return msg.Answer(strings.Join(lines, "\n")).DoVoid(ctx)
+	}, withCommand("access_list")...)
+}
+
+func joinAccessSubjects(subjects []AccessSubject) string {
+	vs := make([]string, len(subjects))
+	for i, subject := range subjects {
+		vs[i] = subject.String()
+	}
+
+	return strings.Join(vs, ", ")
+}
@@ -0,0 +1,246 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccessSubject(t *testing.T) {
+	for _, test := range []struct {
+		Input   string
+		Subject AccessSubject
+		Error   string
+	}{
+		{Input: "123", Subject: "user:123"},
+		{Input: "-100123", Subject: "chat:-100123"},
+		{Input: "@UserName", Subject: "username:username"},
+		{Input: "user:1", Subject: "user:1"},
+		{Input: "chat:-1", Subject: "chat:-1"},
+		{Input: "username:@name", Subject: "username:name"},
+		{Input: "abc", Error: `invalid user id: "abc"`},
+		{Input: "0", Error: `invalid user id: "0"`},
+		{Input: "@", Error: "empty username"},
+		{Input: "group:1", Error: `unknown access subject kind: "group"`},
+	} {
+		subject, err := ParseAccessSubject(test.Input)
+		if test.Error != "" {
+			assert.EqualError(t, err, test.Error, test.Input)
+		} else {
+			require.NoError(t, err, test.Input)
+			assert.Equal(t, test.Subject, subject, test.Input)
+		}
+	}
+}
+
+func TestAccessStoreMemory(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewAccessStoreMemory(
+		AccessEntry{Subject: AccessUser(1), Rule: AccessRuleAllow},
+		AccessEntry{Subject: AccessUser(2), Rule: AccessRuleNone},
+	)
+
+	rule, err := store.Get(ctx, AccessUser(1))
+	require.NoError(t, err)
+	assert.Equal(t, AccessRuleAllow, rule)
+
+	require.NoError(t, store.Set(ctx, AccessChat(-100), AccessRuleDeny))
+	require.NoError(t, store.Set(ctx, AccessUser(1), AccessRuleNone))
+
+	entries, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []AccessEntry{{Subject: AccessChat(-100), Rule: AccessRuleDeny}}, entries)
+
+	store.Replace([]AccessEntry{{Subject: AccessUsername("admin"), Rule: AccessRuleAllow}})
+
+	entries, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []AccessEntry{{Subject: "username:admin", Rule: AccessRuleAllow}}, entries)
+}
+
+func TestAccessControl(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewAccessStoreMemory(
+		AccessEntry{Subject: AccessUser(1), Rule: AccessRuleAllow},
+		AccessEntry{Subject: AccessUsername("friend"), Rule: AccessRuleAllow},
+		AccessEntry{Subject: AccessChat(-100), Rule: AccessRuleAllow},
+		AccessEntry{Subject: AccessUser(3), Rule: AccessRuleDeny},
+	)
+
+	newMessage := func(chatID tg.ChatID, from tg.User) *Update {
+		return &Update{Update: &tg.Update{Message: &tg.Message{
+			Chat: tg.Chat{ID: chatID},
+			From: &from,
+		}}}
+	}
+
+	for _, test := range []struct {
+		Name         string
+		Update       *Update
+		Allowed      bool
+		DefaultAllow bool
+	}{
+		{"AllowedUser", newMessage(1, tg.User{ID: 1}), true, false},
+		{"AllowedUsername", newMessage(2, tg.User{ID: 2, Username: "Friend"}), true, false},
+		{"AllowedChat", newMessage(-100, tg.User{ID: 4}), true, false},
+		{"DeniedUserInAllowedChat", newMessage(-100, tg.User{ID: 3}), false, false},
+		{"Stranger", newMessage(5, tg.User{ID: 5}), false, false},
+		{"StrangerDefaultAllow", newMessage(5, tg.User{ID: 5}), true, true},
+		{"DeniedDefaultAllow", newMessage(3, tg.User{ID: 3}), false, true},
+		{"CallbackQuery", &Update{Update: &tg.Update{CallbackQuery: &tg.CallbackQuery{From: tg.User{ID: 1}}}}, true, false},
+		{"NoSubjects", &Update{Update: &tg.Update{Poll: &tg.Poll{}}}, true, false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			ac := NewAccessControl(store, WithAccessControlDefaultAllow(test.DefaultAllow))
+
+			allowed, err := ac.Check(ctx, test.Update)
+			require.NoError(t, err)
+			assert.Equal(t, test.Allowed, allowed)
+
+			allowed, err = ac.Allowed().Allow(ctx, test.Update)
+			require.NoError(t, err)
+			assert.Equal(t, test.Allowed, allowed)
+
+			_, _, err = ac.Guard(ctx, test.Update)
+			if test.Allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrDropUpdate)
+			}
+		})
+	}
+}
+
+func TestAccessControl_Guard(t *testing.T) {
+	var replies []string
+
+	testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+		ac := NewAccessControl(NewAccessStoreMemory(), WithAccessControlReply("access denied"))
+
+		var called bool
+
+		router := NewRouter().
+			GlobalUse(ac.Guard).
+			Message(func(ctx context.Context, msg *MessageUpdate) error {
+				called = true
+				return nil
+			}).
+			CallbackQuery(func(ctx context.Context, cbq *CallbackQueryUpdate) error {
+				called = true
+				return nil
+			})
+
+		require.NoError(t, router.Handle(ctx, &Update{Client: client, Update: &tg.Update{Message: &tg.Message{
+			Chat: tg.Chat{ID: 5},
+			From: &tg.User{ID: 5},
+		}}}))
+
+		require.NoError(t, router.Handle(ctx, &Update{Client: client, Update: &tg.Update{CallbackQuery: &tg.CallbackQuery{
+			ID:   "cbq",
+			From: tg.User{ID: 5},
+		}}}))
+
+		assert.False(t, called)
+		assert.Equal(t, []string{"sendMessage: access denied", "answerCallbackQuery: access denied"}, replies)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot12345:secret/sendMessage":
+			replies = append(replies, "sendMessage: "+r.FormValue("text"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":5,"type":"private"}}}`))
+		case "/bot12345:secret/answerCallbackQuery":
+			replies = append(replies, "answerCallbackQuery: "+r.FormValue("text"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	})
+}
+
+func TestAccessControl_RegisterCommands(t *testing.T) {
+	var replies []string
+
+	testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+		store := NewAccessStoreMemory()
+		ac := NewAccessControl(store)
+
+		isAdmin := FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+			return update.Message.From.ID == 1, nil
+		})
+
+		router := ac.RegisterCommands(NewRouter(), isAdmin)
+
+		handle := func(from tg.UserID, text string) {
+			err := router.Handle(ctx, &Update{Client: client, Update: &tg.Update{Message: &tg.Message{
+				Chat: tg.Chat{ID: tg.ChatID(from)},
+				From: &tg.User{ID: from},
+				Text: text,
+			}}})
+			require.NoError(t, err)
+		}
+
+		handle(1, "/access_list")
+		handle(1, "/access_allow 2 @Friend")
+		handle(1, "/access_deny -100")
+		handle(1, "/access_remove 2")
+		handle(1, "/access_allow bad")
+		handle(1, "/access_deny")
+		handle(2, "/access_allow 2")
+		handle(1, "/access_list")
+
+		assert.Equal(t, []string{
+			"access lists are empty",
+			"allowed: user:2, username:friend",
+			"denied: chat:-100",
+			"removed: user:2",
+			`invalid user id: "bad"`,
+			"missing argument <subject>\nUsage: /access_deny <subject> [subjects...]",
+			"deny chat:-100\nallow username:friend",
+		}, replies)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot12345:secret/getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":12345,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		case "/bot12345:secret/sendMessage":
+			replies = append(replies, r.FormValue("text"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	})
+}
+
+func TestAccessControl_RegisterCommandsNotAdmin(t *testing.T) {
+	testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+		store := NewAccessStoreMemory()
+		ac := NewAccessControl(store)
+
+		isAdmin := FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+			return update.Message.From.ID == 1, nil
+		})
+
+		router := ac.RegisterCommands(NewRouter(), isAdmin)
+
+		err := router.Handle(ctx, &Update{Client: client, Update: &tg.Update{Message: &tg.Message{
+			Chat: tg.Chat{ID: 2},
+			From: &tg.User{ID: 2},
+			Text: "/access_allow 2",
+		}}})
+		require.NoError(t, err)
+
+		rule, err := store.Get(ctx, AccessUser(2))
+		require.NoError(t, err)
+		assert.Equal(t, AccessRuleNone, rule)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexcepted call '%s'", r.URL.Path)
+	})
+
+	assert.Panics(t, func() {
+		NewAccessControl(NewAccessStoreMemory()).RegisterCommands(NewRouter(), nil)
+	})
+}
@@ -2,10 +2,14 @@ package tgb
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding"
+	"encoding/base64"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nosefu/go-tg"
 )
@@ -133,10 +137,53 @@ func (p *CallbackDataCodec) getFloatFieldPrecOrDefault(field reflect.StructField
 	return prec, nil
 }
 
+// callbackDataEscape is used to escape delimiter and itself in values of callback data.
+const callbackDataEscape = '\\'
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isCallbackDataText reports whether value is encoded as text.
+// Methods are checked on pointer type, so both value and pointer receivers are supported
+// and encoding is consistent with decoding.
+func isCallbackDataText(value reflect.Value) bool {
+	ptr := reflect.PointerTo(value.Type())
+
+	return value.CanInterface() &&
+		ptr.Implements(textMarshalerType) &&
+		ptr.Implements(textUnmarshalerType)
+}
+
 // MarshalCallbackData serializes a struct into callback data.
 // This data will be in format prefix:field_value_1:field_value_2:...:field_value_n
-// Only plain structures are supported.
+//
+// Supported field types:
+//   - bool, ints (including time.Duration), uints, floats and strings;
+//   - time.Time, as unix time in seconds, zero time is encoded as empty value;
+//   - types implementing both encoding.TextMarshaler and encoding.TextUnmarshaler;
+//   - nested structs, fields of which are flattened;
+//   - arrays, encoded as values of elements;
+//   - slices, encoded as length followed by values of elements;
+//   - pointers, encoded as 0 for nil or 1 followed by value.
+//
+// Delimiter and backslash in values are escaped with backslash.
 func (p *CallbackDataCodec) Encode(src any) (string, error) {
+	result, err := p.encode(src)
+	if err != nil {
+		return "", err
+	}
+
+	if !p.disableLengthCheck && len(result) > callbackDataMaxLen {
+		return "", &CallbackDataIsTooLongError{Length: len(result)}
+	}
+
+	return result, nil
+}
+
+func (p *CallbackDataCodec) encode(src any) (string, error) {
 	structValue := reflect.ValueOf(src)
 
 	if structValue.Type().Kind() == reflect.Ptr {
@@ -151,67 +198,193 @@ func (p *CallbackDataCodec) Encode(src any) (string, error) {
 		return "", fmt.Errorf("src should be a struct")
 	}
 
+	values, err := p.encodeStruct(nil, structValue)
+	if err != nil {
+		return "", err
+	}
+
 	var result strings.Builder
 
-	fieldsCount := structValue.NumField()
+	for i, value := range values {
+		if i > 0 {
+			result.WriteRune(p.delimiter)
+		}
+
+		p.writeEscaped(&result, value)
+	}
+
+	return result.String(), nil
+}
 
+func (p *CallbackDataCodec) writeEscaped(dst *strings.Builder, value string) {
+	for _, r := range value {
+		if r == p.delimiter || r == callbackDataEscape {
+			dst.WriteRune(callbackDataEscape)
+		}
+
+		dst.WriteRune(r)
+	}
+}
+
+// split splits data by not escaped delimiter and unescapes values.
+func (p *CallbackDataCodec) split(data string) []string {
+	var (
+		values  []string
+		value   strings.Builder
+		escaped bool
+	)
+
+	for _, r := range data {
+		switch {
+		case escaped:
+			value.WriteRune(r)
+			escaped = false
+		case r == callbackDataEscape:
+			escaped = true
+		case r == p.delimiter:
+			values = append(values, value.String())
+			value.Reset()
+		default:
+			value.WriteRune(r)
+		}
+	}
+
+	return append(values, value.String())
+}
+
+func (p *CallbackDataCodec) encodeStruct(values []string, structValue reflect.Value) ([]string, error) {
 	structType := structValue.Type()
 
-	for i := 0; i < fieldsCount; i++ {
-		if i > 0 {
-			result.WriteRune(p.delimiter)
+	for i := 0; i < structValue.NumField(); i++ {
+		var err error
+
+		values, err = p.encodeValue(values, structValue.Field(i), structType.Field(i))
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		field := structValue.Field(i)
-		structField := structType.Field(i)
+	return values, nil
+}
 
-		switch field.Kind() {
-		case reflect.Bool:
-			if field.Bool() {
-				result.WriteString("1")
-			} else {
-				result.WriteString("0")
-			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			base, err := p.getIntFieldBaseOrDefault(structField)
-			if err != nil {
-				return "", fmt.Errorf("field %v: %w", structField.Name, err)
-			}
+func (p *CallbackDataCodec) encodeValue(values []string, field reflect.Value, structField reflect.StructField) ([]string, error) {
+	switch {
+	case field.Type() == timeType && field.CanInterface():
+		t := field.Interface().(time.Time)
+		if t.IsZero() {
+			return append(values, ""), nil
+		}
 
-			result.WriteString(strconv.FormatInt(field.Int(), base))
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			base, err := p.getIntFieldBaseOrDefault(structField)
-			if err != nil {
-				return "", fmt.Errorf("field %v: %w", structField.Name, err)
-			}
+		base, err := p.getIntFieldBaseOrDefault(structField)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
 
-			result.WriteString(strconv.FormatUint(field.Uint(), base))
-		case reflect.String:
-			result.WriteString(field.String())
-		case reflect.Float32, reflect.Float64:
-			format, err := p.getFloatFieldFmtOrDefault(structField)
-			if err != nil {
-				return "", fmt.Errorf("field %v: %w", structField.Name, err)
-			}
+		return append(values, strconv.FormatInt(t.Unix(), base)), nil
+	case field.Kind() == reflect.Ptr:
+		if field.IsNil() {
+			return append(values, "0"), nil
+		}
+
+		return p.encodeValue(append(values, "1"), field.Elem(), structField)
+	case isCallbackDataText(field):
+		// methods can have pointer receivers, so value is copied if not addressable
+		ptr := reflect.New(field.Type())
+		if field.CanAddr() {
+			ptr = field.Addr()
+		} else {
+			ptr.Elem().Set(field)
+		}
+
+		text, err := ptr.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		return append(values, string(text)), nil
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		if field.Bool() {
+			values = append(values, "1")
+		} else {
+			values = append(values, "0")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		base, err := p.getIntFieldBaseOrDefault(structField)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		values = append(values, strconv.FormatInt(field.Int(), base))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		base, err := p.getIntFieldBaseOrDefault(structField)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		values = append(values, strconv.FormatUint(field.Uint(), base))
+	case reflect.String:
+		values = append(values, field.String())
+	case reflect.Float32, reflect.Float64:
+		format, err := p.getFloatFieldFmtOrDefault(structField)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
 
-			prec, err := p.getFloatFieldPrecOrDefault(structField)
+		prec, err := p.getFloatFieldPrecOrDefault(structField)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		values = append(values, strconv.FormatFloat(field.Float(), format, prec, 64))
+	case reflect.Struct:
+		var err error
+
+		values, err = p.encodeStruct(values, field)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+	case reflect.Slice, reflect.Array:
+		if field.Kind() == reflect.Slice {
+			values = append(values, strconv.FormatInt(int64(field.Len()), p.intBase))
+		}
+
+		for i := 0; i < field.Len(); i++ {
+			var err error
+
+			values, err = p.encodeValue(values, field.Index(i), structField)
 			if err != nil {
-				return "", fmt.Errorf("field %v: %w", structField.Name, err)
+				return nil, err
 			}
-
-			result.WriteString(strconv.FormatFloat(field.Float(), format, prec, 64))
-		default:
-			return "", fmt.Errorf("unsupported field type: %v", field.Kind())
 		}
+	default:
+		return nil, fmt.Errorf("unsupported field type: %v", field.Kind())
 	}
 
-	if !p.disableLengthCheck && result.Len() > callbackDataMaxLen {
-		return "", &CallbackDataIsTooLongError{Length: result.Len()}
+	return values, nil
+}
+
+// callbackDataReader reads values of callback data one by one.
+type callbackDataReader struct {
+	values []string
+	pos    int
+}
+
+func (r *callbackDataReader) next() (string, error) {
+	if r.pos >= len(r.values) {
+		return "", fmt.Errorf("invalid data length: got %v values, expected more", len(r.values))
 	}
 
-	return result.String(), nil
+	value := r.values[r.pos]
+	r.pos++
+
+	return value, nil
 }
 
+// Decode deserializes callback data into a struct pointed by dst.
+// See [CallbackDataCodec.Encode] for supported field types.
 func (p *CallbackDataCodec) Decode(data string, dst any) error {
 	structValue := reflect.ValueOf(dst)
 
@@ -225,68 +398,163 @@ func (p *CallbackDataCodec) Decode(data string, dst any) error {
 		return fmt.Errorf("dst should be a pointer to a struct")
 	}
 
-	fieldsCount := structValue.NumField()
+	reader := &callbackDataReader{
+		values: p.split(data),
+	}
 
-	structType := structValue.Type()
+	if err := p.decodeStruct(reader, structValue); err != nil {
+		return err
+	}
 
-	var values []string
-	if len(data) > 0 {
-		values = strings.Split(data, string(p.delimiter))
+	// empty data is a single empty value or no values at all
+	if reader.pos != len(reader.values) && !(data == "" && reader.pos == 0) {
+		return fmt.Errorf("invalid data length: expected %v, got %v", reader.pos, len(reader.values))
 	}
 
-	if len(values) != fieldsCount {
-		return fmt.Errorf("invalid data length: expected %v, got %v", fieldsCount, len(values))
+	return nil
+}
+
+func (p *CallbackDataCodec) decodeStruct(reader *callbackDataReader, structValue reflect.Value) error {
+	structType := structValue.Type()
+
+	for i := 0; i < structValue.NumField(); i++ {
+		if err := p.decodeValue(reader, structValue.Field(i), structType.Field(i)); err != nil {
+			return err
+		}
 	}
 
-	for i := 0; i < fieldsCount; i++ {
-		field := structValue.Field(i)
-		structField := structType.Field(i)
+	return nil
+}
 
-		switch field.Kind() {
-		case reflect.Bool:
-			if values[i] == "1" {
-				field.SetBool(true)
-			} else if values[i] == "0" {
-				field.SetBool(false)
-			} else {
-				return fmt.Errorf("invalid bool value: %v", values[i])
-			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			base, err := p.getIntFieldBaseOrDefault(structField)
-			if err != nil {
-				return fmt.Errorf("field %v: %w", structField.Name, err)
-			}
+func (p *CallbackDataCodec) decodeValue(reader *callbackDataReader, field reflect.Value, structField reflect.StructField) error {
+	// nested structs and arrays don't have own values
+	switch {
+	case field.Type() == timeType || field.Kind() == reflect.Ptr:
+	case isCallbackDataText(field):
+	case field.Kind() == reflect.Struct:
+		if err := p.decodeStruct(reader, field); err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
 
-			value, err := strconv.ParseInt(values[i], base, 64)
-			if err != nil {
-				return fmt.Errorf("field %v: %w", structField.Name, err)
+		return nil
+	case field.Kind() == reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			if err := p.decodeValue(reader, field.Index(i), structField); err != nil {
+				return err
 			}
+		}
 
-			field.SetInt(value)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			base, err := p.getIntFieldBaseOrDefault(structField)
-			if err != nil {
-				return fmt.Errorf("field %v: %w", structField.Name, err)
-			}
+		return nil
+	}
 
-			value, err := strconv.ParseUint(values[i], base, 64)
-			if err != nil {
-				return fmt.Errorf("field %v: %w", structField.Name, err)
-			}
+	value, err := reader.next()
+	if err != nil {
+		return err
+	}
 
-			field.SetUint(value)
-		case reflect.String:
-			field.SetString(values[i])
-		case reflect.Float32, reflect.Float64:
-			value, err := strconv.ParseFloat(values[i], 64)
-			if err != nil {
-				return fmt.Errorf("field %v: %w", structField.Name, err)
+	switch {
+	case field.Type() == timeType:
+		if value == "" {
+			field.Set(reflect.ValueOf(time.Time{}))
+			return nil
+		}
+
+		base, err := p.getIntFieldBaseOrDefault(structField)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		unix, err := strconv.ParseInt(value, base, 64)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		field.Set(reflect.ValueOf(time.Unix(unix, 0)))
+
+		return nil
+	case field.Kind() == reflect.Ptr:
+		switch value {
+		case "0":
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		case "1":
+			elem := reflect.New(field.Type().Elem())
+			if err := p.decodeValue(reader, elem.Elem(), structField); err != nil {
+				return err
 			}
 
-			field.SetFloat(value)
+			field.Set(elem)
+
+			return nil
 		default:
-			return fmt.Errorf("unsupported field type: %v", field.Kind())
+			return fmt.Errorf("field %v: invalid pointer flag: %v", structField.Name, value)
+		}
+	case isCallbackDataText(field):
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
 		}
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		if value == "1" {
+			field.SetBool(true)
+		} else if value == "0" {
+			field.SetBool(false)
+		} else {
+			return fmt.Errorf("invalid bool value: %v", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		base, err := p.getIntFieldBaseOrDefault(structField)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		v, err := strconv.ParseInt(value, base, 64)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		base, err := p.getIntFieldBaseOrDefault(structField)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		v, err := strconv.ParseUint(value, base, 64)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		field.SetUint(v)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", structField.Name, err)
+		}
+
+		field.SetFloat(v)
+	case reflect.Slice:
+		n, err := strconv.ParseInt(value, p.intBase, 64)
+		if err != nil || n < 0 || n > int64(len(reader.values)) {
+			return fmt.Errorf("field %v: invalid slice length: %v", structField.Name, value)
+		}
+
+		slice := reflect.MakeSlice(field.Type(), int(n), int(n))
+		for i := 0; i < int(n); i++ {
+			if err := p.decodeValue(reader, slice.Index(i), structField); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+	default:
+		return fmt.Errorf("unsupported field type: %v", field.Kind())
 	}
 
 	return nil
@@ -304,16 +572,107 @@ func DecodeCallbackData(data string, dst any) error {
 	return DefaultCallbackDataCodec.Decode(data, dst)
 }
 
+// CallbackDataStore define interface for storage of callback data which doesn't fit into a button.
+// It has the same shape as session.Store, so any session store can be used.
+// See [WithCallbackDataFilterStore].
+type CallbackDataStore interface {
+	// Set saves a value by key.
+	Set(ctx context.Context, key string, value []byte) error
+
+	// Get returns a value by key.
+	// If the value is not found, returns nil and nil.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Del deletes a value by key.
+	Del(ctx context.Context, key string) error
+}
+
+const (
+	callbackDataStoreKeyPrefix = "callback_data:"
+	callbackDataStoreKeySize   = 8
+)
+
+// callbackDataStoreMarker returns marker of callback data saved in the store.
+// Escape is followed only by delimiter or escape in encoded data,
+// so escape followed by other char never occurs in it.
+func callbackDataStoreMarker(delimiter rune) string {
+	if delimiter == '$' {
+		return "\\#"
+	}
+
+	return "\\$"
+}
+
+// ErrCallbackDataNotFound is returned when callback data saved in the store is expired or not found.
+var ErrCallbackDataNotFound = fmt.Errorf("callback data not found")
+
 type CallbackDataFilter[T any] struct {
 	prefix string
 	codec  *CallbackDataCodec
+
+	store    CallbackDataStore
+	storeTTL time.Duration
+}
+
+// CallbackDataFilterOption configures [CallbackDataFilter].
+// [CallbackDataCodecOption] is also accepted and configures codec of the filter.
+type CallbackDataFilterOption interface {
+	applyCallbackDataFilter(settings *callbackDataFilterSettings)
+}
+
+type callbackDataFilterSettings struct {
+	codecOpts []CallbackDataCodecOption
+	store     CallbackDataStore
+	storeTTL  time.Duration
+}
+
+type callbackDataFilterOptionFunc func(settings *callbackDataFilterSettings)
+
+func (opt callbackDataFilterOptionFunc) applyCallbackDataFilter(settings *callbackDataFilterSettings) {
+	opt(settings)
+}
+
+func (opt CallbackDataCodecOption) applyCallbackDataFilter(settings *callbackDataFilterSettings) {
+	settings.codecOpts = append(settings.codecOpts, opt)
+}
+
+// WithCallbackDataFilterStore sets a store for callback data which doesn't fit into a button.
+// Such data is saved in the store under a short random key and only the key is sent in the button.
+// Decoding looks up the data transparently.
+//
+// Note that each rendered button with oversized data adds an entry to the store.
+// Entries are never deleted by the filter, except expired ones on access (see [WithCallbackDataFilterStoreTTL]),
+// so entries of buttons which are never clicked are kept forever.
+// Use a store which expires keys itself (e.g. Redis with TTL) or clean it up periodically,
+// in-memory store (e.g. session.StoreMemory) grows unbounded.
+func WithCallbackDataFilterStore(store CallbackDataStore) CallbackDataFilterOption {
+	return callbackDataFilterOptionFunc(func(settings *callbackDataFilterSettings) {
+		settings.store = store
+	})
+}
+
+// WithCallbackDataFilterStoreTTL sets a lifetime of callback data saved in the store.
+// Expired data can't be decoded and is deleted on access only, see [WithCallbackDataFilterStore].
+// Default is 0, data never expires.
+func WithCallbackDataFilterStoreTTL(ttl time.Duration) CallbackDataFilterOption {
+	return callbackDataFilterOptionFunc(func(settings *callbackDataFilterSettings) {
+		settings.storeTTL = ttl
+	})
 }
 
 // NewCallbackDataFilter creates a new CallbackDataPrefixFilter with default options.
-func NewCallbackDataFilter[T any](prefix string, opts ...CallbackDataCodecOption) *CallbackDataFilter[T] {
+func NewCallbackDataFilter[T any](prefix string, opts ...CallbackDataFilterOption) *CallbackDataFilter[T] {
+	var settings callbackDataFilterSettings
+
+	for _, opt := range opts {
+		opt.applyCallbackDataFilter(&settings)
+	}
+
 	return &CallbackDataFilter[T]{
-		prefix: prefix,
-		codec:  NewCallackDataCodec(opts...),
+		prefix:   prefix,
+		codec:    NewCallackDataCodec(settings.codecOpts...),
+		store:    settings.store,
+		storeTTL: settings.storeTTL,
 	}
 }
 
@@ -341,7 +700,24 @@ func (p *CallbackDataFilter[T]) Button(text string, v T) (tg.InlineKeyboardButto
 
 // Encode serializes a struct into callback data using the filter's parser.
 func (p *CallbackDataFilter[T]) Encode(src T) (string, error) {
-	body, err := p.codec.Encode(src)
+	return p.EncodeContext(context.Background(), src)
+}
+
+// EncodeContext serializes a struct into callback data using the filter's parser.
+// If store is set by [WithCallbackDataFilterStore] and data is too long,
+// it's saved in the store and callback data contains only the key.
+func (p *CallbackDataFilter[T]) EncodeContext(ctx context.Context, src T) (string, error) {
+	var (
+		body string
+		err  error
+	)
+
+	if p.store != nil {
+		body, err = p.codec.encode(src)
+	} else {
+		body, err = p.codec.Encode(src)
+	}
+
 	if err != nil {
 		return "", fmt.Errorf("body decode: %w", err)
 	}
@@ -350,7 +726,18 @@ func (p *CallbackDataFilter[T]) Encode(src T) (string, error) {
 
 	builder.WriteString(p.prefix)
 	builder.WriteRune(p.codec.delimiter)
-	builder.WriteString(body)
+
+	if p.store != nil && builder.Len()+len(body) > callbackDataMaxLen {
+		key, err := p.storeBody(ctx, body)
+		if err != nil {
+			return "", fmt.Errorf("store body: %w", err)
+		}
+
+		builder.WriteString(callbackDataStoreMarker(p.codec.delimiter))
+		builder.WriteString(key)
+	} else {
+		builder.WriteString(body)
+	}
 
 	return builder.String(), nil
 }
@@ -359,6 +746,12 @@ func (p *CallbackDataFilter[T]) Encode(src T) (string, error) {
 // It checks if the data has the correct prefix.
 // If not, an error will be returned.
 func (p *CallbackDataFilter[T]) Decode(data string) (T, error) {
+	return p.DecodeContext(context.Background(), data)
+}
+
+// DecodeContext deserializes callback data into a struct using the filter's codec.
+// Data saved in the store is looked up by key, see [CallbackDataFilter.EncodeContext].
+func (p *CallbackDataFilter[T]) DecodeContext(ctx context.Context, data string) (T, error) {
 	var dst T
 	if !strings.HasPrefix(data, p.prefix) {
 		return dst, fmt.Errorf("invalid prefix: expected %v, got %v", p.prefix, data)
@@ -366,6 +759,15 @@ func (p *CallbackDataFilter[T]) Decode(data string) (T, error) {
 
 	data = strings.TrimPrefix(data, p.prefix+string(p.codec.delimiter))
 
+	if key := strings.TrimPrefix(data, callbackDataStoreMarker(p.codec.delimiter)); key != data && p.store != nil {
+		body, err := p.loadBody(ctx, key)
+		if err != nil {
+			return dst, fmt.Errorf("load body: %w", err)
+		}
+
+		data = body
+	}
+
 	err := p.codec.Decode(data, &dst)
 	if err != nil {
 		return dst, fmt.Errorf("body decode: %w", err)
@@ -374,6 +776,59 @@ func (p *CallbackDataFilter[T]) Decode(data string) (T, error) {
 	return dst, nil
 }
 
+func (p *CallbackDataFilter[T]) storeBody(ctx context.Context, body string) (string, error) {
+	random := make([]byte, callbackDataStoreKeySize)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+
+	key := base64.RawURLEncoding.EncodeToString(random)
+
+	var expiresAt int64
+	if p.storeTTL > 0 {
+		expiresAt = time.Now().Add(p.storeTTL).Unix()
+	}
+
+	value := strconv.FormatInt(expiresAt, 36) + ":" + body
+
+	if err := p.store.Set(ctx, callbackDataStoreKeyPrefix+key, []byte(value)); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (p *CallbackDataFilter[T]) loadBody(ctx context.Context, key string) (string, error) {
+	value, err := p.store.Get(ctx, callbackDataStoreKeyPrefix+key)
+	if err != nil {
+		return "", err
+	}
+
+	if value == nil {
+		return "", ErrCallbackDataNotFound
+	}
+
+	expiresAtStr, body, ok := strings.Cut(string(value), ":")
+	if !ok {
+		return "", fmt.Errorf("invalid stored value of key %v", key)
+	}
+
+	expiresAt, err := strconv.ParseInt(expiresAtStr, 36, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid stored value of key %v: %w", key, err)
+	}
+
+	if expiresAt > 0 && time.Now().Unix() >= expiresAt {
+		if err := p.store.Del(ctx, callbackDataStoreKeyPrefix+key); err != nil {
+			return "", fmt.Errorf("delete expired key %v: %w", key, err)
+		}
+
+		return "", ErrCallbackDataNotFound
+	}
+
+	return body, nil
+}
+
 // Filter returns a tgb.Filter for the given prefix
 // It checks if the data has the correct prefix.
 // If not, it will return false.
@@ -403,7 +858,7 @@ func (p *CallbackDataFilter[T]) FilterFunc(check func(v T) bool) Filter {
 			return false, nil
 		}
 
-		v, err := p.Decode(update.CallbackQuery.Data)
+		v, err := p.DecodeContext(ctx, update.CallbackQuery.Data)
 		if err != nil {
 			return false, fmt.Errorf("decode: %w", err)
 		}
@@ -422,7 +877,7 @@ type CallbackDataFilterHandler[T any] func(ctx context.Context, cbq *CallbackQue
 // If an error occurs while decoding, it will be returned and passed handler will not be called.
 func (p *CallbackDataFilter[T]) Handler(handler CallbackDataFilterHandler[T]) CallbackQueryHandler {
 	return func(ctx context.Context, cqu *CallbackQueryUpdate) error {
-		cbd, err := p.Decode(cqu.CallbackQuery.Data)
+		cbd, err := p.DecodeContext(ctx, cqu.CallbackQuery.Data)
 		if err != nil {
 			return fmt.Errorf("decode: %w", err)
 		}
@@ -2,7 +2,11 @@ package tgb
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	tg "github.com/nosefu/go-tg"
 	"github.com/stretchr/testify/assert"
@@ -268,6 +272,164 @@ func TestCallbackDataParserDecode(t *testing.T) {
 	})
 }
 
+type testCallbackDataText struct {
+	Value string
+}
+
+func (v testCallbackDataText) MarshalText() ([]byte, error) {
+	return []byte("<" + v.Value + ">"), nil
+}
+
+func (v *testCallbackDataText) UnmarshalText(text []byte) error {
+	if len(text) < 2 || text[0] != '<' || text[len(text)-1] != '>' {
+		return fmt.Errorf("invalid text value: %s", text)
+	}
+
+	v.Value = string(text[1 : len(text)-1])
+
+	return nil
+}
+
+// testCallbackDataPoint has methods with pointer receivers only.
+type testCallbackDataPoint struct {
+	X, Y, Z int
+}
+
+func (p *testCallbackDataPoint) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d;%d;%d", p.X, p.Y, p.Z)), nil
+}
+
+func (p *testCallbackDataPoint) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d;%d;%d", &p.X, &p.Y, &p.Z)
+	return err
+}
+
+func TestCallbackDataParserPointerReceiverText(t *testing.T) {
+	type test struct {
+		P testCallbackDataPoint
+		N int
+	}
+
+	src := test{P: testCallbackDataPoint{X: 1, Y: 2, Z: 3}, N: 4}
+
+	// struct passed by value is not addressable
+	data, err := EncodeCallbackData(src)
+	require.NoError(t, err)
+	assert.Equal(t, "1;2;3:4", data)
+
+	data, err = EncodeCallbackData(&src)
+	require.NoError(t, err)
+	assert.Equal(t, "1;2;3:4", data)
+
+	var dst test
+	require.NoError(t, DecodeCallbackData(data, &dst))
+	assert.Equal(t, src, dst)
+}
+
+func TestCallbackDataParserComplexTypes(t *testing.T) {
+	type nested struct {
+		ID   int
+		Name string
+	}
+
+	type test struct {
+		Nested   nested
+		Array    [2]bool
+		Slice    []int
+		Empty    []string
+		Ptr      *int
+		NilPtr   *nested
+		Time     time.Time
+		ZeroTime time.Time
+		Duration time.Duration
+		Text     testCallbackDataText
+		Escaped  string
+	}
+
+	ptr := 10
+
+	src := test{
+		Nested:   nested{ID: 1, Name: "a"},
+		Array:    [2]bool{true, false},
+		Slice:    []int{1, 2, 3},
+		Empty:    []string{},
+		Ptr:      &ptr,
+		Time:     time.Unix(1700000000, 0),
+		Duration: time.Minute,
+		Text:     testCallbackDataText{Value: "txt"},
+		Escaped:  `a:b\c`,
+	}
+
+	data, err := EncodeCallbackData(src)
+	require.NoError(t, err)
+	assert.Equal(t, `1:a:1:0:3:1:2:3:0:1:a:0:s44we8::rkag8ao:<txt>:a\:b\\c`, data)
+
+	var dst test
+	require.NoError(t, DecodeCallbackData(data, &dst))
+	assert.Equal(t, src, dst)
+
+	t.Run("EmptyString", func(t *testing.T) {
+		type test struct {
+			String string
+		}
+
+		var dst test
+		require.NoError(t, DecodeCallbackData("", &dst))
+		assert.Equal(t, test{}, dst)
+	})
+
+	t.Run("TooManyValues", func(t *testing.T) {
+		type test struct {
+			Slice []int
+		}
+
+		var dst test
+		err := DecodeCallbackData("1:1:2", &dst)
+		assert.EqualError(t, err, "invalid data length: expected 2, got 3")
+	})
+
+	t.Run("InvalidSliceLength", func(t *testing.T) {
+		type test struct {
+			Slice []int
+		}
+
+		var dst test
+		err := DecodeCallbackData("5:1", &dst)
+		assert.EqualError(t, err, "field Slice: invalid slice length: 5")
+	})
+
+	t.Run("InvalidPointerFlag", func(t *testing.T) {
+		type test struct {
+			Ptr *int
+		}
+
+		var dst test
+		err := DecodeCallbackData("2:1", &dst)
+		assert.EqualError(t, err, "field Ptr: invalid pointer flag: 2")
+	})
+
+	t.Run("InvalidText", func(t *testing.T) {
+		type test struct {
+			Text testCallbackDataText
+		}
+
+		var dst test
+		err := DecodeCallbackData("txt", &dst)
+		assert.EqualError(t, err, "field Text: invalid text value: txt")
+	})
+
+	t.Run("NestedUnsupportedFieldType", func(t *testing.T) {
+		type test struct {
+			Nested struct {
+				Unsupported chan int
+			}
+		}
+
+		_, err := EncodeCallbackData(test{})
+		assert.EqualError(t, err, "field Nested: unsupported field type: chan")
+	})
+}
+
 func TestCallbackDataFilter(t *testing.T) {
 	t.Run("ButtonError", func(t *testing.T) {
 		type test struct {
@@ -580,3 +742,129 @@ func TestCallbackDataFilter(t *testing.T) {
 		assert.True(t, allowed)
 	})
 }
+
+type testCallbackDataStore map[string][]byte
+
+func (store testCallbackDataStore) Set(ctx context.Context, key string, value []byte) error {
+	store[key] = value
+	return nil
+}
+
+func (store testCallbackDataStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return store[key], nil
+}
+
+func (store testCallbackDataStore) Del(ctx context.Context, key string) error {
+	delete(store, key)
+	return nil
+}
+
+func TestCallbackDataFilterStore(t *testing.T) {
+	type test struct {
+		Query string
+		Page  int
+	}
+
+	long := test{Query: strings.Repeat("x", 64), Page: 2}
+
+	t.Run("Short", func(t *testing.T) {
+		store := testCallbackDataStore{}
+		filter := NewCallbackDataFilter[test]("list", WithCallbackDataFilterStore(store))
+
+		data, err := filter.Encode(test{Query: "x", Page: 2})
+		require.NoError(t, err)
+		assert.Equal(t, "list:x:2", data)
+		assert.Empty(t, store)
+	})
+
+	t.Run("Long", func(t *testing.T) {
+		store := testCallbackDataStore{}
+		filter := NewCallbackDataFilter[test]("list", WithCallbackDataFilterStore(store))
+
+		data, err := filter.Encode(long)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(data, `list:\$`), data)
+		assert.Len(t, data, len(`list:\$`)+11)
+		require.Len(t, store, 1)
+
+		for key, value := range store {
+			assert.Equal(t, "callback_data:"+strings.TrimPrefix(data, `list:\$`), key)
+			assert.Equal(t, "0:"+long.Query+":2", string(value))
+		}
+
+		dst, err := filter.Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, long, dst)
+
+		allowed, err := filter.FilterFunc(func(v test) bool {
+			return v.Page == 2
+		}).Allow(context.Background(), &Update{Update: &tg.Update{
+			CallbackQuery: &tg.CallbackQuery{Data: data},
+		}})
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("LongWithoutStore", func(t *testing.T) {
+		filter := NewCallbackDataFilter[test]("list")
+
+		_, err := filter.Encode(long)
+		var tooLong *CallbackDataIsTooLongError
+		assert.ErrorAs(t, err, &tooLong)
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		store := testCallbackDataStore{}
+		filter := NewCallbackDataFilter[test]("list",
+			WithCallbackDataFilterStore(store),
+			WithCallbackDataFilterStoreTTL(time.Hour),
+		)
+
+		data, err := filter.Encode(long)
+		require.NoError(t, err)
+
+		_, err = filter.Decode(data)
+		require.NoError(t, err)
+
+		key := "callback_data:" + strings.TrimPrefix(data, `list:\$`)
+		store[key] = []byte(strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 36) + ":x:1")
+
+		_, err = filter.Decode(data)
+		assert.ErrorIs(t, err, ErrCallbackDataNotFound)
+		assert.Empty(t, store)
+	})
+
+	t.Run("DollarDelimiter", func(t *testing.T) {
+		store := testCallbackDataStore{}
+		filter := NewCallbackDataFilter[test]("list",
+			WithCallbackDataFilterStore(store),
+			WithCallbackDataCodecDelimiter('$'),
+		)
+
+		// value starting with delimiter is not a store key
+		short := test{Query: "$x", Page: 1}
+
+		data, err := filter.Encode(short)
+		require.NoError(t, err)
+		assert.Equal(t, `list$\$x$1`, data)
+
+		dst, err := filter.Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, short, dst)
+
+		data, err = filter.Encode(long)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(data, `list$\#`), data)
+
+		dst, err = filter.Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, long, dst)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		filter := NewCallbackDataFilter[test]("list", WithCallbackDataFilterStore(testCallbackDataStore{}))
+
+		_, err := filter.Decode(`list:\$unknown`)
+		assert.ErrorIs(t, err, ErrCallbackDataNotFound)
+	})
+}
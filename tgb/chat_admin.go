@@ -0,0 +1,287 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nosefu/go-tg"
+)
+
+// ChatAdminCache caches lists of chat administrators.
+// Lists are fetched with getChatAdministrators and expire after TTL.
+// Concurrent requests of the same chat share single fetch, expired lists are evicted.
+// Use [ChatAdminCache.Watch] to invalidate lists on chat_member updates.
+// It's thread-safe.
+type ChatAdminCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	lock    sync.Mutex
+	chats   map[tg.ChatID]*chatAdminEntry
+	fetches map[tg.ChatID]*chatAdminFetch
+}
+
+type chatAdminEntry struct {
+	admins    []tg.ChatMemberAdministrator
+	fetchedAt time.Time
+}
+
+// chatAdminFetch is a fetch of the chat administrators in progress.
+type chatAdminFetch struct {
+	done   chan struct{}
+	admins []tg.ChatMemberAdministrator
+	err    error
+}
+
+// DefaultChatAdminCache is used by chat admin filters by default.
+var DefaultChatAdminCache = NewChatAdminCache(5 * time.Minute)
+
+// NewChatAdminCache creates a new ChatAdminCache with specified TTL of lists.
+func NewChatAdminCache(ttl time.Duration) *ChatAdminCache {
+	return &ChatAdminCache{
+		ttl:     ttl,
+		now:     time.Now,
+		chats:   make(map[tg.ChatID]*chatAdminEntry),
+		fetches: make(map[tg.ChatID]*chatAdminFetch),
+	}
+}
+
+// Get returns administrators of the chat, from cache if possible.
+// Owner of the chat is returned as administrator with status "creator" and no rights set.
+func (cache *ChatAdminCache) Get(ctx context.Context, client *tg.Client, chatID tg.ChatID) ([]tg.ChatMemberAdministrator, error) {
+	now := cache.now()
+
+	cache.lock.Lock()
+
+	if entry, ok := cache.chats[chatID]; ok && now.Sub(entry.fetchedAt) < cache.ttl {
+		cache.lock.Unlock()
+		return entry.admins, nil
+	}
+
+	// wait for fetch started by other caller
+	if fetch, ok := cache.fetches[chatID]; ok {
+		cache.lock.Unlock()
+
+		select {
+		case <-fetch.done:
+			return fetch.admins, fetch.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	fetch := &chatAdminFetch{done: make(chan struct{})}
+	cache.fetches[chatID] = fetch
+
+	cache.lock.Unlock()
+
+	defer close(fetch.done)
+
+	// tg.ChatMember has no administrator rights, so result is decoded as tg.ChatMemberAdministrator.
+	if err := client.Do(ctx, client.GetChatAdministrators(chatID).Request(), &fetch.admins); err != nil {
+		fetch.admins, fetch.err = nil, fmt.Errorf("get chat administrators: %w", err)
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	// fetch is removed by Invalidate, result can be outdated
+	if cache.fetches[chatID] != fetch {
+		return fetch.admins, fetch.err
+	}
+
+	delete(cache.fetches, chatID)
+
+	if fetch.err == nil {
+		cache.evict(now)
+		cache.chats[chatID] = &chatAdminEntry{
+			admins:    fetch.admins,
+			fetchedAt: now,
+		}
+	}
+
+	return fetch.admins, fetch.err
+}
+
+// evict removes expired lists. Should be called with lock held.
+func (cache *ChatAdminCache) evict(now time.Time) {
+	for chatID, entry := range cache.chats {
+		if now.Sub(entry.fetchedAt) >= cache.ttl {
+			delete(cache.chats, chatID)
+		}
+	}
+}
+
+// Invalidate removes cached list of the chat administrators.
+func (cache *ChatAdminCache) Invalidate(chatID tg.ChatID) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	delete(cache.chats, chatID)
+	delete(cache.fetches, chatID)
+}
+
+// Watch is a [GlobalMiddlewareFunc] which invalidates cached list of the chat administrators,
+// when chat_member or my_chat_member update is about administrator.
+//
+// Keep in mind, chat_member updates are not sent by Telegram by default,
+// it should be enabled via allowed updates.
+func (cache *ChatAdminCache) Watch(ctx context.Context, update *Update) (context.Context, *Update, error) {
+	for _, member := range []*tg.ChatMemberUpdated{update.ChatMember, update.MyChatMember} {
+		if member != nil && (isChatMemberAdmin(member.OldChatMember) || isChatMemberAdmin(member.NewChatMember)) {
+			cache.Invalidate(member.Chat.ID)
+		}
+	}
+
+	return ctx, update, nil
+}
+
+func isChatMemberAdmin(member tg.ChatMember) bool {
+	return member.Status == "creator" || member.Status == "administrator"
+}
+
+// ChatAdminRight is an administrator right, see [HasAdminRight].
+type ChatAdminRight int
+
+const (
+	ChatAdminRightManageChat ChatAdminRight = iota
+	ChatAdminRightDeleteMessages
+	ChatAdminRightManageVideoChats
+	ChatAdminRightRestrictMembers
+	ChatAdminRightPromoteMembers
+	ChatAdminRightChangeInfo
+	ChatAdminRightInviteUsers
+	ChatAdminRightPostStories
+	ChatAdminRightEditStories
+	ChatAdminRightDeleteStories
+	ChatAdminRightPostMessages
+	ChatAdminRightEditMessages
+	ChatAdminRightPinMessages
+	ChatAdminRightManageTopics
+)
+
+// has reports whether administrator has the right. Owner has all rights.
+func (right ChatAdminRight) has(admin *tg.ChatMemberAdministrator) bool {
+	if admin.Status == "creator" {
+		return true
+	}
+
+	switch right {
+	case ChatAdminRightManageChat:
+		return admin.CanManageChat
+	case ChatAdminRightDeleteMessages:
+		return admin.CanDeleteMessages
+	case ChatAdminRightManageVideoChats:
+		return admin.CanManageVideoChats
+	case ChatAdminRightRestrictMembers:
+		return admin.CanRestrictMembers
+	case ChatAdminRightPromoteMembers:
+		return admin.CanPromoteMembers
+	case ChatAdminRightChangeInfo:
+		return admin.CanChangeInfo
+	case ChatAdminRightInviteUsers:
+		return admin.CanInviteUsers
+	case ChatAdminRightPostStories:
+		return admin.CanPostStories
+	case ChatAdminRightEditStories:
+		return admin.CanEditStories
+	case ChatAdminRightDeleteStories:
+		return admin.CanDeleteStories
+	case ChatAdminRightPostMessages:
+		return admin.CanPostMessages
+	case ChatAdminRightEditMessages:
+		return admin.CanEditMessages
+	case ChatAdminRightPinMessages:
+		return admin.CanPinMessages
+	case ChatAdminRightManageTopics:
+		return admin.CanManageTopics
+	default:
+		return false
+	}
+}
+
+// chatAdminFilter checks that sender of the update is administrator of the chat.
+type chatAdminFilter struct {
+	cache  *ChatAdminCache
+	rights []ChatAdminRight
+}
+
+// IsChatAdmin adds filter which allows updates from administrators of the chat.
+// Private chats are not allowed.
+//
+// Messages sent on behalf of the chat (by anonymous administrators) are allowed too.
+//
+// Lists of administrators are cached in [DefaultChatAdminCache],
+// use [ChatAdminCache.IsChatAdmin] to use own cache.
+func IsChatAdmin() Filter {
+	return DefaultChatAdminCache.IsChatAdmin()
+}
+
+// HasAdminRight adds filter which allows updates from administrators of the chat with all specified rights.
+// Owner of the chat has all rights.
+//
+// Actual sender of messages sent on behalf of the chat is unknown,
+// so they are allowed if any anonymous administrator has the rights.
+//
+// Lists of administrators are cached in [DefaultChatAdminCache],
+// use [ChatAdminCache.HasAdminRight] to use own cache.
+func HasAdminRight(rights ...ChatAdminRight) Filter {
+	return DefaultChatAdminCache.HasAdminRight(rights...)
+}
+
+// IsChatAdmin is like [IsChatAdmin], but uses the cache.
+func (cache *ChatAdminCache) IsChatAdmin() Filter {
+	return &chatAdminFilter{cache: cache}
+}
+
+// HasAdminRight is like [HasAdminRight], but uses the cache.
+func (cache *ChatAdminCache) HasAdminRight(rights ...ChatAdminRight) Filter {
+	return &chatAdminFilter{cache: cache, rights: rights}
+}
+
+func (filter *chatAdminFilter) hasRights(admin *tg.ChatMemberAdministrator) bool {
+	for _, right := range filter.rights {
+		if !right.has(admin) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (filter *chatAdminFilter) Allow(ctx context.Context, update *Update) (bool, error) {
+	chat := update.Chat()
+	if chat == nil || chat.Type == tg.ChatTypePrivate {
+		return false, nil
+	}
+
+	var anonymous bool
+
+	user := getUpdateUser(update)
+	if msg := update.Msg(); msg != nil && update.CallbackQuery == nil && msg.SenderChat != nil && msg.SenderChat.ID == chat.ID {
+		anonymous = true
+	} else if user == nil {
+		return false, nil
+	}
+
+	admins, err := filter.cache.Get(ctx, update.Client, chat.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range admins {
+		admin := &admins[i]
+
+		if anonymous {
+			if admin.IsAnonymous && filter.hasRights(admin) {
+				return true, nil
+			}
+		} else if admin.User.ID == user.ID {
+			return filter.hasRights(admin), nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,197 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatAdmin(t *testing.T) {
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getChatAdministrators":
+			calls++
+			assert.Equal(t, "-100", r.FormValue("chat_id"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":[
+				{"status":"creator","user":{"id":1,"is_bot":false,"first_name":"Owner"},"is_anonymous":false},
+				{"status":"administrator","user":{"id":2,"is_bot":false,"first_name":"Moderator"},"can_restrict_members":true,"can_delete_messages":true},
+				{"status":"administrator","user":{"id":3,"is_bot":false,"first_name":"Anonymous"},"is_anonymous":true,"can_delete_messages":true}
+			]}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+
+	group := tg.Chat{ID: -100, Type: tg.ChatTypeSupergroup}
+
+	newMessage := func(userID tg.UserID) *Update {
+		return &Update{
+			Client: client,
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: group,
+				From: &tg.User{ID: userID},
+			}},
+		}
+	}
+
+	anonymous := &Update{
+		Client: client,
+		Update: &tg.Update{Message: &tg.Message{
+			Chat:       group,
+			From:       &tg.User{ID: 1087968824},
+			SenderChat: &group,
+		}},
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cache := NewChatAdminCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	allow := func(filter Filter, update *Update) bool {
+		allowed, err := filter.Allow(ctx, update)
+		require.NoError(t, err)
+		return allowed
+	}
+
+	t.Run("IsChatAdmin", func(t *testing.T) {
+		filter := cache.IsChatAdmin()
+
+		assert.True(t, allow(filter, newMessage(1)))
+		assert.True(t, allow(filter, newMessage(2)))
+		assert.False(t, allow(filter, newMessage(4)))
+		assert.True(t, allow(filter, anonymous))
+
+		private := &Update{Update: &tg.Update{Message: &tg.Message{
+			Chat: tg.Chat{ID: 1, Type: tg.ChatTypePrivate},
+			From: &tg.User{ID: 1},
+		}}}
+		assert.False(t, allow(filter, private))
+
+		assert.Equal(t, 1, calls, "list is cached")
+	})
+
+	t.Run("HasAdminRight", func(t *testing.T) {
+		ban := cache.HasAdminRight(ChatAdminRightRestrictMembers)
+
+		assert.True(t, allow(ban, newMessage(1)), "owner has all rights")
+		assert.True(t, allow(ban, newMessage(2)))
+		assert.False(t, allow(ban, anonymous))
+
+		del := cache.HasAdminRight(ChatAdminRightDeleteMessages, ChatAdminRightRestrictMembers)
+		assert.True(t, allow(del, newMessage(2)))
+
+		pin := cache.HasAdminRight(ChatAdminRightPinMessages)
+		assert.False(t, allow(pin, newMessage(2)))
+
+		assert.True(t, allow(cache.HasAdminRight(ChatAdminRightDeleteMessages), anonymous))
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		now = now.Add(time.Minute)
+
+		assert.True(t, allow(cache.IsChatAdmin(), newMessage(1)))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		member := &Update{Update: &tg.Update{ChatMember: &tg.ChatMemberUpdated{
+			Chat:          group,
+			OldChatMember: tg.ChatMember{Status: "member"},
+			NewChatMember: tg.ChatMember{Status: "member"},
+		}}}
+
+		_, _, err := cache.Watch(ctx, member)
+		require.NoError(t, err)
+		assert.True(t, allow(cache.IsChatAdmin(), newMessage(1)))
+		assert.Equal(t, 2, calls, "not admin change")
+
+		member.ChatMember.NewChatMember.Status = "administrator"
+
+		_, _, err = cache.Watch(ctx, member)
+		require.NoError(t, err)
+		assert.True(t, allow(cache.IsChatAdmin(), newMessage(1)))
+		assert.Equal(t, 3, calls, "cache is invalidated")
+	})
+}
+
+func TestChatAdminCache_Concurrent(t *testing.T) {
+	var calls int32
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		atomic.AddInt32(&calls, 1)
+		<-release
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":[{"status":"creator","user":{"id":1,"is_bot":false,"first_name":"Owner"}}]}`))
+	}))
+	defer server.Close()
+
+	client := tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cache := NewChatAdminCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			admins, err := cache.Get(context.Background(), client, -100)
+			assert.NoError(t, err)
+			assert.Len(t, admins, 1)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) == 1
+	}, time.Second, time.Millisecond)
+
+	// let other callers join the fetch in progress
+	time.Sleep(time.Millisecond * 20)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls), "concurrent misses share single fetch")
+
+	t.Run("Evict", func(t *testing.T) {
+		now = now.Add(time.Minute)
+
+		_, err := cache.Get(context.Background(), client, -200)
+		require.NoError(t, err)
+
+		cache.lock.Lock()
+		defer cache.lock.Unlock()
+
+		assert.Len(t, cache.chats, 1, "expired list is evicted")
+		assert.Contains(t, cache.chats, tg.ChatID(-200))
+		assert.Empty(t, cache.fetches)
+	})
+}
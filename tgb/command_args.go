@@ -0,0 +1,520 @@
+package tgb
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CommandArgs is a parsed command with arguments.
+// It's set to context by [Command] filter, see [CommandArgsFromContext].
+type CommandArgs struct {
+	// Prefix is the command prefix, e.g. "/".
+	Prefix string
+
+	// Command is the command without prefix and mention, as it was sent.
+	Command string
+
+	// Mention is the bot username the command is addressed to (/start@bot), if any.
+	Mention string
+
+	// Raw is the text after command.
+	Raw string
+
+	// Args are the arguments split by spaces.
+	// Quotes ("...", '...', “...”, «...») and backslash escapes are supported, like in shell.
+	Args []string
+}
+
+// CommandArgsFromContext returns arguments of the command matched by [Command] filter.
+// It returns nil if handler is not filtered by Command.
+func CommandArgsFromContext(ctx context.Context) *CommandArgs {
+	args, _ := getFilterValue(ctx, commandArgsContextKey).(*CommandArgs)
+	return args
+}
+
+// parseCommandArgs parses text of the command. First rune of text is used as prefix.
+func parseCommandArgs(text string) *CommandArgs {
+	if text == "" {
+		return nil
+	}
+
+	full, raw := text, ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		full, raw = text[:i], strings.TrimSpace(text[i:])
+	}
+
+	_, size := utf8.DecodeRuneInString(full)
+	command, mention, _ := strings.Cut(full[size:], "@")
+
+	return &CommandArgs{
+		Prefix:  full[:size],
+		Command: command,
+		Mention: mention,
+		Raw:     raw,
+		Args:    splitCommandArgs(raw),
+	}
+}
+
+// commandArgsQuotes maps opening quotes to closing ones.
+var commandArgsQuotes = map[rune]rune{
+	'"':  '"',
+	'\'': '\'',
+	'“':  '”',
+	'«':  '»',
+}
+
+// splitCommandArgs splits s to arguments like shell does.
+// Unterminated quote lasts until the end of s.
+func splitCommandArgs(s string) []string {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case commandArgsQuotes[r] != 0:
+			quote = commandArgsQuotes[r]
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		current.WriteRune('\\')
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+// CommandUsageError is returned by [CommandArgs.Decode] when arguments don't match the struct.
+type CommandUsageError struct {
+	// Usage is the usage line of the command, e.g. "/mute <user> <duration> [reason...] [--silent]".
+	Usage string
+
+	Err error
+}
+
+func (err *CommandUsageError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *CommandUsageError) Unwrap() error {
+	return err.Err
+}
+
+// commandArgField describes field of the struct to decode arguments.
+type commandArgField struct {
+	index    int
+	name     string
+	flag     bool
+	optional bool
+	rest     bool
+	isBool   bool
+}
+
+func (field *commandArgField) usage() string {
+	switch {
+	case field.flag && field.isBool:
+		return "[--" + field.name + "]"
+	case field.flag:
+		return "[--" + field.name + " <value>]"
+	case field.rest:
+		return "[" + field.name + "...]"
+	case field.optional:
+		return "[" + field.name + "]"
+	default:
+		return "<" + field.name + ">"
+	}
+}
+
+type commandArgsSpec struct {
+	positional []*commandArgField
+	flags      []*commandArgField
+}
+
+func (spec *commandArgsSpec) flag(name string) *commandArgField {
+	for _, field := range spec.flags {
+		if field.name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func (spec *commandArgsSpec) usage(command string) string {
+	parts := []string{command}
+
+	for _, field := range spec.positional {
+		parts = append(parts, field.usage())
+	}
+
+	for _, field := range spec.flags {
+		parts = append(parts, field.usage())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// getCommandArgsSpec parses `cmd` tags of struct fields:
+//
+//	User     string        `cmd:"user"`             // required positional argument
+//	Duration time.Duration `cmd:"duration,optional"` // optional positional argument
+//	Reason   string        `cmd:"reason,rest"`      // rest of arguments, joined by space
+//	Silent   bool          `cmd:"--silent"`         // flag
+//
+// Pointer fields and rest argument are optional. Fields without tag are ignored.
+func getCommandArgsSpec(typ reflect.Type) (*commandArgsSpec, error) {
+	spec := &commandArgsSpec{}
+
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+
+		tag, ok := structField.Tag.Lookup("cmd")
+		if !ok || tag == "-" || !structField.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fieldType := structField.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		field := &commandArgField{
+			index:    i,
+			name:     strings.TrimPrefix(name, "--"),
+			flag:     strings.HasPrefix(name, "--"),
+			optional: structField.Type.Kind() == reflect.Pointer,
+			isBool:   fieldType.Kind() == reflect.Bool,
+		}
+
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "optional":
+				field.optional = true
+			case "rest":
+				field.rest = true
+				field.optional = true
+			case "":
+			default:
+				return nil, fmt.Errorf("field %s: unknown option %q", structField.Name, opt)
+			}
+		}
+
+		if field.name == "" {
+			return nil, fmt.Errorf("field %s: empty name", structField.Name)
+		}
+
+		if field.flag {
+			if field.rest {
+				return nil, fmt.Errorf("field %s: flag can't be rest", structField.Name)
+			}
+
+			spec.flags = append(spec.flags, field)
+			continue
+		}
+
+		if n := len(spec.positional); n > 0 {
+			prev := spec.positional[n-1]
+
+			if prev.rest {
+				return nil, fmt.Errorf("field %s: rest argument should be the last", structField.Name)
+			}
+
+			if prev.optional && !field.optional {
+				return nil, fmt.Errorf("field %s: required argument after optional", structField.Name)
+			}
+		}
+
+		spec.positional = append(spec.positional, field)
+	}
+
+	return spec, nil
+}
+
+// Decode decodes arguments into struct pointed by v, see `cmd` tag format below.
+// If arguments don't match the struct, [CommandUsageError] is returned.
+//
+//	type MuteArgs struct {
+//		User     string        `cmd:"user"`              // required positional argument
+//		Duration time.Duration `cmd:"duration,optional"` // optional positional argument
+//		Reason   []string      `cmd:"reason,rest"`       // optional rest of arguments, string is joined by space
+//		Silent   bool          `cmd:"--silent"`          // flag, non bool flag value is --name=value or --name value
+//	}
+//
+// Supported field types are strings, numbers, bools, time.Duration, encoding.TextUnmarshaler,
+// pointers to them (optional argument) and slices of them for rest argument.
+// Arguments after "--" are not treated as flags.
+func (args *CommandArgs) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode command args: expected pointer to struct, got %T", v)
+	}
+
+	rv = rv.Elem()
+
+	spec, err := getCommandArgsSpec(rv.Type())
+	if err != nil {
+		return fmt.Errorf("decode command args: %w", err)
+	}
+
+	usageErr := func(format string, a ...any) error {
+		return &CommandUsageError{
+			Usage: spec.usage(args.Prefix + args.Command),
+			Err:   fmt.Errorf(format, a...),
+		}
+	}
+
+	var positional []string
+
+	tokens := args.Args
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		if token == "--" {
+			positional = append(positional, tokens[i+1:]...)
+			break
+		}
+
+		if !strings.HasPrefix(token, "--") || len(token) == 2 {
+			positional = append(positional, token)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(token[2:], "=")
+
+		field := spec.flag(name)
+		if field == nil {
+			return usageErr("unknown flag --%s", name)
+		}
+
+		if !hasValue {
+			if field.isBool {
+				value = "true"
+			} else if i+1 < len(tokens) {
+				i++
+				value = tokens[i]
+			} else {
+				return usageErr("flag --%s requires value", name)
+			}
+		}
+
+		if err := setStringValue(rv.Field(field.index), value); err != nil {
+			return usageErr("invalid value %q for --%s: %w", value, name, err)
+		}
+	}
+
+	for _, field := range spec.positional {
+		if len(positional) == 0 {
+			if !field.optional {
+				return usageErr("missing argument <%s>", field.name)
+			}
+			continue
+		}
+
+		fv := rv.Field(field.index)
+
+		if field.rest {
+			if err := setCommandArgRest(fv, positional); err != nil {
+				return usageErr("invalid value for <%s...>: %w", field.name, err)
+			}
+
+			positional = nil
+			break
+		}
+
+		if err := setStringValue(fv, positional[0]); err != nil {
+			return usageErr("invalid value %q for <%s>: %w", positional[0], field.name, err)
+		}
+
+		positional = positional[1:]
+	}
+
+	if len(positional) > 0 {
+		return usageErr("too many arguments")
+	}
+
+	return nil
+}
+
+func setCommandArgRest(fv reflect.Value, values []string) error {
+	if fv.Kind() != reflect.Slice {
+		return setStringValue(fv, strings.Join(values, " "))
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+
+	for i, value := range values {
+		if err := setStringValue(slice.Index(i), value); err != nil {
+			return fmt.Errorf("%q: %w", value, err)
+		}
+	}
+
+	fv.Set(slice)
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setStringValue parses value according to type of fv and sets it.
+func setStringValue(fv reflect.Value, value string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+
+		if err := setStringValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		fv.Set(ptr)
+
+		return nil
+	}
+
+	if unmarshaler, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+
+		fv.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("invalid boolean")
+		}
+		fv.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New("invalid integer")
+		}
+		fv.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New("invalid unsigned integer")
+		}
+		fv.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return errors.New("invalid number")
+		}
+		fv.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
+
+// CommandHandlerOption is an option for [CommandHandler].
+type CommandHandlerOption func(*commandHandler)
+
+type commandHandler struct {
+	onUsageError func(ctx context.Context, msg *MessageUpdate, err *CommandUsageError) error
+}
+
+// WithCommandHandlerOnUsageError sets function which is called when arguments don't match.
+// By default, error and usage are sent in reply to the message.
+func WithCommandHandlerOnUsageError(fn func(ctx context.Context, msg *MessageUpdate, err *CommandUsageError) error) CommandHandlerOption {
+	return func(handler *commandHandler) {
+		handler.onUsageError = fn
+	}
+}
+
+func replyCommandUsageError(ctx context.Context, msg *MessageUpdate, err *CommandUsageError) error {
+	return msg.Update.Reply(ctx, msg.Answer(err.Error()+"\nUsage: "+err.Usage))
+}
+
+// CommandHandler creates [MessageHandler] which decodes arguments of the command into T
+// and passes them to handler. See [CommandArgs.Decode] for supported struct tags.
+//
+// Arguments are taken from the context, if handler is filtered by [Command],
+// otherwise they are parsed from text of the message.
+//
+//	router.Message(tgb.CommandHandler(func(ctx context.Context, msg *tgb.MessageUpdate, args MuteArgs) error {
+//		// ...
+//	}), tgb.Command("mute"))
+func CommandHandler[T any](handler func(ctx context.Context, msg *MessageUpdate, args T) error, opts ...CommandHandlerOption) MessageHandler {
+	h := &commandHandler{
+		onUsageError: replyCommandUsageError,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return func(ctx context.Context, msg *MessageUpdate) error {
+		args := CommandArgsFromContext(ctx)
+		if args == nil {
+			args = parseCommandArgs(msg.Text)
+		}
+
+		if args == nil {
+			return fmt.Errorf("command handler: message is not a command")
+		}
+
+		var v T
+
+		if err := args.Decode(&v); err != nil {
+			var usageErr *CommandUsageError
+			if errors.As(err, &usageErr) {
+				return h.onUsageError(ctx, msg, usageErr)
+			}
+
+			return fmt.Errorf("command handler: %w", err)
+		}
+
+		return handler(ctx, msg, v)
+	}
+}
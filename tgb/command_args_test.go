@@ -0,0 +1,224 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommandArgs(t *testing.T) {
+	for _, test := range []struct {
+		Input string
+		Args  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"a b  c", []string{"a", "b", "c"}},
+		{`"hello world" 'single quoted' x`, []string{"hello world", "single quoted", "x"}},
+		{`“smart quotes” «guillemets»`, []string{"smart quotes", "guillemets"}},
+		{`a\ b c\"d`, []string{"a b", `c"d`}},
+		{`'no \escape'`, []string{`no \escape`}},
+		{`""`, []string{""}},
+		{`"unterminated quote`, []string{"unterminated quote"}},
+		{"multi\nline\targs", []string{"multi", "line", "args"}},
+	} {
+		assert.Equal(t, test.Args, splitCommandArgs(test.Input), test.Input)
+	}
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	assert.Nil(t, parseCommandArgs(""))
+
+	assert.Equal(t, &CommandArgs{
+		Prefix:  "/",
+		Command: "mute",
+		Mention: "bot",
+		Raw:     `@user 1h "too much spam"`,
+		Args:    []string{"@user", "1h", "too much spam"},
+	}, parseCommandArgs("/mute@bot   @user 1h \"too much spam\"  "))
+
+	assert.Equal(t, &CommandArgs{
+		Prefix:  "!",
+		Command: "start",
+	}, parseCommandArgs("!start"))
+}
+
+type testMuteArgs struct {
+	User     string         `cmd:"user"`
+	Duration *time.Duration `cmd:"duration"`
+	Reason   string         `cmd:"reason,rest"`
+	Silent   bool           `cmd:"--silent"`
+	Notify   int            `cmd:"--notify"`
+	Ignored  string
+}
+
+type testTextArgs struct {
+	IP    netip.Addr `cmd:"ip"`
+	Ports []uint16   `cmd:"ports,rest"`
+}
+
+func TestCommandArgs_Decode(t *testing.T) {
+	decode := func(text string, v any) error {
+		return parseCommandArgs(text).Decode(v)
+	}
+
+	t.Run("Full", func(t *testing.T) {
+		var args testMuteArgs
+		require.NoError(t, decode(`/mute @user 1h30m --silent too "much spam" --notify=5`, &args))
+
+		duration := 90 * time.Minute
+		assert.Equal(t, testMuteArgs{
+			User:     "@user",
+			Duration: &duration,
+			Reason:   "too much spam",
+			Silent:   true,
+			Notify:   5,
+		}, args)
+	})
+
+	t.Run("Optional", func(t *testing.T) {
+		var args testMuteArgs
+		require.NoError(t, decode(`/mute @user --notify 3`, &args))
+		assert.Equal(t, testMuteArgs{User: "@user", Notify: 3}, args)
+	})
+
+	t.Run("DoubleDash", func(t *testing.T) {
+		var args testMuteArgs
+		require.NoError(t, decode(`/mute @user 1h -- --silent`, &args))
+		assert.Equal(t, "--silent", args.Reason)
+		assert.False(t, args.Silent)
+	})
+
+	t.Run("TextUnmarshalerAndSlice", func(t *testing.T) {
+		var args testTextArgs
+		require.NoError(t, decode(`/scan 10.0.0.1 80 443`, &args))
+		assert.Equal(t, netip.MustParseAddr("10.0.0.1"), args.IP)
+		assert.Equal(t, []uint16{80, 443}, args.Ports)
+
+		args = testTextArgs{}
+		require.NoError(t, decode(`/scan 10.0.0.1`, &args))
+		assert.Nil(t, args.Ports)
+	})
+
+	t.Run("UsageErrors", func(t *testing.T) {
+		for _, test := range []struct {
+			Text  string
+			Error string
+		}{
+			{"/mute", "missing argument <user>"},
+			{"/mute @user soon", `invalid value "soon" for <duration>: invalid duration`},
+			{"/mute @user --unknown", "unknown flag --unknown"},
+			{"/mute @user --notify", "flag --notify requires value"},
+			{"/mute @user --notify=x", `invalid value "x" for --notify: invalid integer`},
+		} {
+			var args testMuteArgs
+
+			err := decode(test.Text, &args)
+
+			var usageErr *CommandUsageError
+			require.ErrorAs(t, err, &usageErr, test.Text)
+			assert.EqualError(t, usageErr, test.Error)
+			assert.Equal(t, "/mute <user> [duration] [reason...] [--silent] [--notify <value>]", usageErr.Usage)
+		}
+
+		var args testTextArgs
+		err := decode("/scan 10.0.0.1 80 http", &args)
+		assert.EqualError(t, err, `invalid value for <ports...>: "http": invalid unsigned integer`)
+	})
+
+	t.Run("TooManyArguments", func(t *testing.T) {
+		var args struct {
+			A string `cmd:"a"`
+		}
+
+		var usageErr *CommandUsageError
+		require.ErrorAs(t, decode("/cmd a b", &args), &usageErr)
+		assert.EqualError(t, usageErr, "too many arguments")
+		assert.Equal(t, "/cmd <a>", usageErr.Usage)
+	})
+
+	t.Run("InvalidSpec", func(t *testing.T) {
+		var notStruct string
+		assert.Error(t, decode("/cmd", &notStruct))
+
+		var rest struct {
+			A []string `cmd:"a,rest"`
+			B string   `cmd:"b"`
+		}
+		assert.EqualError(t, decode("/cmd", &rest), "decode command args: field B: rest argument should be the last")
+
+		var order struct {
+			A string `cmd:"a,optional"`
+			B string `cmd:"b"`
+		}
+		assert.EqualError(t, decode("/cmd", &order), "decode command args: field B: required argument after optional")
+	})
+}
+
+func TestCommandHandler(t *testing.T) {
+	var replies []string
+
+	testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+		var calls []testMuteArgs
+
+		router := NewRouter().
+			Message(CommandHandler(func(ctx context.Context, msg *MessageUpdate, args testMuteArgs) error {
+				calls = append(calls, args)
+				assert.Equal(t, "mute", CommandArgsFromContext(ctx).Command)
+				return nil
+			}), Command("mute")).
+			Message(CommandHandler(func(ctx context.Context, msg *MessageUpdate, args testTextArgs) error {
+				assert.Nil(t, CommandArgsFromContext(ctx))
+				assert.Equal(t, []uint16{80}, args.Ports)
+				return nil
+			}))
+
+		handle := func(text string) {
+			err := router.Handle(ctx, &Update{Client: client, Update: &tg.Update{Message: &tg.Message{
+				Chat: tg.Chat{ID: 1},
+				Text: text,
+			}}})
+			require.NoError(t, err)
+		}
+
+		handle("/mute @user --silent")
+		handle("/mute")
+		handle("/scan 10.0.0.1 80")
+
+		assert.Equal(t, []testMuteArgs{{User: "@user", Silent: true}}, calls)
+		assert.Equal(t, []string{
+			"missing argument <user>\nUsage: /mute <user> [duration] [reason...] [--silent] [--notify <value>]",
+		}, replies)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot12345:secret/getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		case "/bot12345:secret/sendMessage":
+			replies = append(replies, r.FormValue("text"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	})
+}
+
+func TestCommandArgsFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, CommandArgsFromContext(ctx))
+
+	ctx = withFilterValues(ctx)
+	setFilterValue(ctx, commandArgsContextKey, &CommandArgs{Command: "outer"})
+
+	inner := withFilterValues(ctx)
+	assert.Equal(t, "outer", CommandArgsFromContext(inner).Command, "values of outer filters are available")
+
+	setFilterValue(inner, commandArgsContextKey, &CommandArgs{Command: "inner"})
+	assert.Equal(t, "inner", CommandArgsFromContext(inner).Command)
+	assert.Equal(t, "outer", CommandArgsFromContext(ctx).Command)
+}
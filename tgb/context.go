@@ -0,0 +1,145 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type contextKey int
+
+const (
+	recoverContextKey contextKey = iota
+	filterValuesContextKey
+	commandArgsContextKey
+	regexpMatchContextKey
+	rolesContextKey
+	updateDoneContextKey
+)
+
+// updateDone holds callbacks called by the top-level [Router] when update handling is finished.
+type updateDone struct {
+	lock      sync.Mutex
+	callbacks []func(ctx context.Context, err error) error
+}
+
+// withUpdateDone returns context with new holder of done callbacks.
+// If context already has one, nil holder is returned, callbacks are called by its owner.
+func withUpdateDone(ctx context.Context) (context.Context, *updateDone) {
+	if _, ok := ctx.Value(updateDoneContextKey).(*updateDone); ok {
+		return ctx, nil
+	}
+
+	done := &updateDone{}
+
+	return context.WithValue(ctx, updateDoneContextKey, done), done
+}
+
+// onUpdateDone registers callback called with result of update handling.
+// Returns false if context has no holder of done callbacks.
+func onUpdateDone(ctx context.Context, callback func(ctx context.Context, err error) error) bool {
+	done, ok := ctx.Value(updateDoneContextKey).(*updateDone)
+	if !ok {
+		return false
+	}
+
+	done.lock.Lock()
+	defer done.lock.Unlock()
+
+	done.callbacks = append(done.callbacks, callback)
+
+	return true
+}
+
+// run calls registered callbacks and returns err extended with their errors.
+func (done *updateDone) run(ctx context.Context, err error) error {
+	done.lock.Lock()
+	callbacks := done.callbacks
+	done.lock.Unlock()
+
+	for _, callback := range callbacks {
+		if cbErr := callback(ctx, err); cbErr != nil {
+			if err == nil {
+				err = cbErr
+			} else {
+				err = fmt.Errorf("%w; %v", err, cbErr)
+			}
+		}
+	}
+
+	return err
+}
+
+// filterValues holds values set by filters, e.g. parsed command arguments.
+// Filter can't change context, so holder is put to context before filter is called,
+// and passed to handler if filter allows the update.
+type filterValues struct {
+	parent *filterValues
+
+	lock   sync.Mutex
+	values map[contextKey]any
+}
+
+// withFilterValues returns context with new holder of filter values.
+// Values set by filters of the outer handlers (e.g. Router groups) are still available.
+func withFilterValues(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(filterValuesContextKey).(*filterValues)
+
+	return context.WithValue(ctx, filterValuesContextKey, &filterValues{
+		parent: parent,
+	})
+}
+
+// setFilterValue sets value to the holder of filter values, if context has one.
+func setFilterValue(ctx context.Context, key contextKey, value any) {
+	holder, ok := ctx.Value(filterValuesContextKey).(*filterValues)
+	if !ok {
+		return
+	}
+
+	holder.lock.Lock()
+	defer holder.lock.Unlock()
+
+	if holder.values == nil {
+		holder.values = make(map[contextKey]any)
+	}
+
+	holder.values[key] = value
+}
+
+// getFilterValue returns value set by filter, the nearest one wins.
+func getFilterValue(ctx context.Context, key contextKey) any {
+	holder, _ := ctx.Value(filterValuesContextKey).(*filterValues)
+
+	for ; holder != nil; holder = holder.parent {
+		holder.lock.Lock()
+		value, ok := holder.values[key]
+		holder.lock.Unlock()
+
+		if ok {
+			return value
+		}
+	}
+
+	return nil
+}
+
+// detachedContext keeps values of the parent context, but not its cancellation and deadline.
+// Replacement of context.WithoutCancel, which is not available in Go 1.18.
+type detachedContext struct {
+	parent context.Context
+}
+
+// withoutCancel returns context which is not canceled when parent is canceled.
+func withoutCancel(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (ctx detachedContext) Value(key any) any {
+	return ctx.parent.Value(key)
+}
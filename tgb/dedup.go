@@ -0,0 +1,182 @@
+package tgb
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupStore define interface for storage of seen updates.
+// Shared store (e.g. Redis with SET NX EX) allows to deduplicate updates across replicas.
+// See [DedupStoreMemory] for example.
+type DedupStore interface {
+	// Seen marks key as seen and reports whether it was seen before.
+	// Check and mark should be atomic.
+	Seen(ctx context.Context, key string) (bool, error)
+
+	// Forget unmarks key, so update can be handled again.
+	// It's called when handling of the update failed.
+	Forget(ctx context.Context, key string) error
+}
+
+// DedupStoreMemory is a bounded in-memory store of seen updates.
+// Key is forgotten when it's older than ttl or store size exceeds limit (oldest first).
+// It implements [DedupStore] and is thread-safe.
+type DedupStoreMemory struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	lock  sync.Mutex
+	order *list.List // of *dedupEntry, oldest first
+	keys  map[string]*list.Element
+}
+
+type dedupEntry struct {
+	key  string
+	seen time.Time
+}
+
+var _ DedupStore = (*DedupStoreMemory)(nil)
+
+// NewDedupStoreMemory creates a new DedupStoreMemory.
+// Zero size or ttl disables corresponding limit.
+func NewDedupStoreMemory(size int, ttl time.Duration) *DedupStoreMemory {
+	return &DedupStoreMemory{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		keys:  make(map[string]*list.Element),
+	}
+}
+
+func (store *DedupStoreMemory) Seen(ctx context.Context, key string) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := store.now()
+
+	store.evict(now)
+
+	if _, ok := store.keys[key]; ok {
+		return true, nil
+	}
+
+	store.keys[key] = store.order.PushBack(&dedupEntry{
+		key:  key,
+		seen: now,
+	})
+
+	if store.size > 0 && store.order.Len() > store.size {
+		store.remove(store.order.Front())
+	}
+
+	return false, nil
+}
+
+func (store *DedupStoreMemory) Forget(ctx context.Context, key string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if el, ok := store.keys[key]; ok {
+		store.remove(el)
+	}
+
+	return nil
+}
+
+func (store *DedupStoreMemory) evict(now time.Time) {
+	if store.ttl <= 0 {
+		return
+	}
+
+	for el := store.order.Front(); el != nil; el = store.order.Front() {
+		if now.Sub(el.Value.(*dedupEntry).seen) < store.ttl {
+			return
+		}
+
+		store.remove(el)
+	}
+}
+
+func (store *DedupStoreMemory) remove(el *list.Element) {
+	store.order.Remove(el)
+	delete(store.keys, el.Value.(*dedupEntry).key)
+}
+
+// DedupOption is an option for [Dedup] middleware.
+type DedupOption func(*dedup)
+
+type dedup struct {
+	store   DedupStore
+	keyFunc func(update *Update) string
+}
+
+// WithDedupKeyFunc sets function to get the deduplication key from update.
+// By default [DedupKeyFunc] is used.
+func WithDedupKeyFunc(keyFunc func(update *Update) string) DedupOption {
+	return func(d *dedup) {
+		d.keyFunc = keyFunc
+	}
+}
+
+// DedupKeyFunc returns key of the update as bot id and update id separated by colon.
+// Bot id is included, so single store can be shared by multiple bots.
+func DedupKeyFunc(update *Update) string {
+	var botID string
+	if update.Client != nil {
+		botID, _, _ = strings.Cut(update.Client.Token(), ":")
+	}
+
+	return botID + ":" + strconv.Itoa(update.ID)
+}
+
+// Dedup creates middleware that drops already seen updates.
+// Telegram can deliver same update twice, e.g. webhook is retried after slow response,
+// or poller is restarted before offset is confirmed.
+//
+// Duplicates are dropped silently, see [ErrDropUpdate].
+// If handling of the update fails (Router returns error), key is forgotten,
+// so retry of the update (e.g. [WithPollerAck]) is not dropped.
+func Dedup(store DedupStore, opts ...DedupOption) GlobalMiddlewareFunc {
+	d := &dedup{
+		store:   store,
+		keyFunc: DedupKeyFunc,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+		key := d.keyFunc(update)
+
+		seen, err := d.store.Seen(ctx, key)
+		if err != nil {
+			return ctx, update, fmt.Errorf("dedup: %w", err)
+		}
+
+		if seen {
+			return ctx, update, ErrDropUpdate
+		}
+
+		onUpdateDone(ctx, func(ctx context.Context, err error) error {
+			if err == nil {
+				return nil
+			}
+
+			if err := d.store.Forget(ctx, key); err != nil {
+				return fmt.Errorf("dedup forget: %w", err)
+			}
+
+			return nil
+		})
+
+		return ctx, update, nil
+	}
+}
@@ -0,0 +1,217 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupStoreMemory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Size", func(t *testing.T) {
+		store := NewDedupStoreMemory(2, 0)
+
+		for _, key := range []string{"a", "b", "c"} {
+			seen, err := store.Seen(ctx, key)
+			require.NoError(t, err)
+			assert.False(t, seen, key)
+		}
+
+		seen, err := store.Seen(ctx, "c")
+		require.NoError(t, err)
+		assert.True(t, seen)
+
+		// a is evicted as oldest
+		seen, err = store.Seen(ctx, "a")
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		store := NewDedupStoreMemory(2, 0)
+
+		seen, err := store.Seen(ctx, "a")
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		require.NoError(t, store.Forget(ctx, "a"))
+		require.NoError(t, store.Forget(ctx, "unknown"))
+
+		seen, err = store.Seen(ctx, "a")
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		now := time.Now()
+
+		store := NewDedupStoreMemory(0, time.Minute)
+		store.now = func() time.Time { return now }
+
+		seen, err := store.Seen(ctx, "a")
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		now = now.Add(time.Second * 30)
+
+		seen, err = store.Seen(ctx, "a")
+		require.NoError(t, err)
+		assert.True(t, seen)
+
+		now = now.Add(time.Second * 31)
+
+		seen, err = store.Seen(ctx, "a")
+		require.NoError(t, err)
+		assert.False(t, seen)
+		assert.Len(t, store.keys, 1)
+	})
+}
+
+type dedupStoreFunc func(ctx context.Context, key string) (bool, error)
+
+func (f dedupStoreFunc) Seen(ctx context.Context, key string) (bool, error) {
+	return f(ctx, key)
+}
+
+func (f dedupStoreFunc) Forget(ctx context.Context, key string) error {
+	return nil
+}
+
+func TestDedup(t *testing.T) {
+	t.Run("Router", func(t *testing.T) {
+		var handled int
+
+		router := NewRouter().
+			GlobalUse(Dedup(NewDedupStoreMemory(100, time.Minute))).
+			Message(func(ctx context.Context, msg *MessageUpdate) error {
+				handled++
+				return nil
+			}).
+			Error(func(ctx context.Context, update *Update, err error) error {
+				t.Fatal("error handler should not be called")
+				return err
+			})
+
+		client := tg.New("1234:secret")
+
+		for _, id := range []int{1, 2, 1} {
+			err := router.Handle(context.Background(), &Update{
+				Update: &tg.Update{ID: id, Message: &tg.Message{}},
+				Client: client,
+			})
+			require.NoError(t, err)
+		}
+
+		// same update id of other bot is not duplicate
+		err := router.Handle(context.Background(), &Update{
+			Update: &tg.Update{ID: 1, Message: &tg.Message{}},
+			Client: tg.New("5678:secret"),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, handled)
+	})
+
+	t.Run("KeyFunc", func(t *testing.T) {
+		var keys []string
+
+		mw := Dedup(
+			dedupStoreFunc(func(ctx context.Context, key string) (bool, error) {
+				keys = append(keys, key)
+				return false, nil
+			}),
+			WithDedupKeyFunc(func(update *Update) string {
+				return "custom"
+			}),
+		)
+
+		_, _, err := mw(context.Background(), &Update{Update: &tg.Update{ID: 1}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"custom"}, keys)
+	})
+
+	t.Run("StoreError", func(t *testing.T) {
+		mw := Dedup(dedupStoreFunc(func(ctx context.Context, key string) (bool, error) {
+			return false, assert.AnError
+		}))
+
+		_, _, err := mw(context.Background(), &Update{Update: &tg.Update{ID: 1}})
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	assert.Equal(t, "1234:42", DedupKeyFunc(&Update{Update: &tg.Update{ID: 42}, Client: tg.New("1234:secret")}))
+}
+
+func TestDedup_PollerAck(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		attempts = map[int]int{}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "/bot1234:secret/getUpdates":
+			switch r.FormValue("offset") {
+			case "10":
+				// update 10 is delivered twice
+				_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":10,"message":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}},{"update_id":10,"message":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}]}`))
+			default:
+				cancel()
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+			}
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	router := NewRouter().
+		GlobalUse(Dedup(NewDedupStoreMemory(100, time.Minute))).
+		Message(func(ctx context.Context, msg *MessageUpdate) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			attempts[msg.Update.ID]++
+
+			// fails on first attempt
+			if attempts[msg.Update.ID] == 1 {
+				return assert.AnError
+			}
+
+			return nil
+		})
+
+	store := NewOffsetStoreMemory()
+	require.NoError(t, store.SetOffset(ctx, 10))
+
+	err := NewPoller(
+		router,
+		tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+		WithPollerRetryAfter(time.Millisecond),
+		WithPollerOffsetStore(store),
+		WithPollerAck(3),
+	).Run(ctx)
+	require.NoError(t, err)
+
+	offset, err := store.GetOffset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 11, offset)
+
+	// retry after failure is handled, duplicate after success is dropped
+	assert.Equal(t, 2, attempts[10])
+}
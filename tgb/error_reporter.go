@@ -0,0 +1,356 @@
+package tgb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nosefu/go-tg"
+)
+
+// ErrorReporter is an [ErrorHandler] which reports errors to a Telegram chat, e.g. admins group.
+// Report contains update type, chat, user, error chain (or panic stack) and the update itself.
+//
+// Reports with same signature are rate limited: only first error per interval is reported,
+// and number of suppressed errors is included into the next report
+// or sent separately at the end of the interval.
+//
+// Use [ErrorReporter.Handle] as error handler:
+//
+//	router.Error(tgb.NewErrorReporter(adminChatID).Handle)
+type ErrorReporter struct {
+	chatID tg.PeerID
+	client *tg.Client
+	logger Logger
+
+	interval      time.Duration
+	timeout       time.Duration
+	maxUpdateLen  int
+	maxStackLen   int
+	signatureFunc func(err error) string
+	next          ErrorHandler
+	now           func() time.Time
+	afterFunc     func(d time.Duration, f func())
+
+	lock    sync.Mutex
+	reports map[string]*errorReport
+}
+
+type errorReport struct {
+	sentAt     time.Time
+	suppressed int
+
+	// client and flush timer of suppressed reports
+	client    *tg.Client
+	scheduled bool
+}
+
+// ErrorReporterOption used to configure the ErrorReporter.
+type ErrorReporterOption func(*ErrorReporter)
+
+// WithErrorReporterClient sets the client used to send reports.
+// By default, client of the update is used.
+func WithErrorReporterClient(client *tg.Client) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.client = client
+	}
+}
+
+// WithErrorReporterLogger sets the logger which will be used to log errors of sending reports.
+func WithErrorReporterLogger(logger Logger) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.logger = logger
+	}
+}
+
+// WithErrorReporterInterval sets the minimal interval between reports with same signature.
+// By default is 1 minute. Zero disables rate limiting.
+func WithErrorReporterInterval(interval time.Duration) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.interval = interval
+	}
+}
+
+// WithErrorReporterTimeout sets the timeout of sending report.
+// Report is sent with context detached from the handler one,
+// so it's sent even if error is caused by handler timeout or cancellation.
+// By default is 10 seconds.
+func WithErrorReporterTimeout(timeout time.Duration) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.timeout = timeout
+	}
+}
+
+// WithErrorReporterMaxUpdateLen sets the maximum length of update JSON in the report.
+// By default is 1024 characters. Zero disables update in the report.
+func WithErrorReporterMaxUpdateLen(n int) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.maxUpdateLen = n
+	}
+}
+
+// WithErrorReporterMaxStackLen sets the maximum length of panic stack in the report.
+// By default is 1536 characters. Zero disables stack in the report.
+func WithErrorReporterMaxStackLen(n int) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.maxStackLen = n
+	}
+}
+
+// WithErrorReporterSignatureFunc sets the function which returns signature of error used for rate limiting.
+// By default [ErrorSignature] is used.
+func WithErrorReporterSignatureFunc(fn func(err error) string) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.signatureFunc = fn
+	}
+}
+
+// WithErrorReporterNext sets the error handler which is called after report is sent,
+// e.g. to log the error. Its result is returned by [ErrorReporter.Handle].
+// By default, error is considered as handled and nil is returned.
+func WithErrorReporterNext(next ErrorHandler) ErrorReporterOption {
+	return func(reporter *ErrorReporter) {
+		reporter.next = next
+	}
+}
+
+// NewErrorReporter creates a new ErrorReporter which sends reports to the chat.
+func NewErrorReporter(chatID tg.PeerID, opts ...ErrorReporterOption) *ErrorReporter {
+	reporter := &ErrorReporter{
+		chatID:        chatID,
+		interval:      time.Minute,
+		timeout:       10 * time.Second,
+		maxUpdateLen:  1024,
+		maxStackLen:   1536,
+		signatureFunc: ErrorSignature,
+		now:           time.Now,
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+		reports: make(map[string]*errorReport),
+	}
+
+	for _, opt := range opts {
+		opt(reporter)
+	}
+
+	return reporter
+}
+
+func (reporter *ErrorReporter) log(format string, args ...any) {
+	if reporter.logger != nil {
+		reporter.logger.Printf("tgb.ErrorReporter: "+format, args...)
+	}
+}
+
+var errorSignatureNumbers = regexp.MustCompile(`\d+`)
+
+// ErrorSignature returns signature of the error: error message with numbers replaced by N,
+// so errors that differ only by ids are considered the same.
+// For [PanicError] signature is based on the panic value.
+func ErrorSignature(err error) string {
+	msg := err.Error()
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		msg = "panic: " + fmt.Sprint(panicErr.Value)
+	}
+
+	return errorSignatureNumbers.ReplaceAllString(msg, "N")
+}
+
+// allow reports whether report with signature should be sent now.
+// It returns number of suppressed reports with same signature since previous report.
+// If report is suppressed, flush of suppressed reports is scheduled to the end of interval.
+func (reporter *ErrorReporter) allow(signature string, client *tg.Client) (bool, int) {
+	if reporter.interval <= 0 {
+		return true, 0
+	}
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	now := reporter.now()
+
+	for key, report := range reporter.reports {
+		if now.Sub(report.sentAt) >= reporter.interval && report.suppressed == 0 {
+			delete(reporter.reports, key)
+		}
+	}
+
+	report, ok := reporter.reports[signature]
+	if !ok {
+		reporter.reports[signature] = &errorReport{sentAt: now}
+		return true, 0
+	}
+
+	if now.Sub(report.sentAt) < reporter.interval {
+		report.suppressed++
+		report.client = client
+
+		if !report.scheduled {
+			report.scheduled = true
+			reporter.afterFunc(report.sentAt.Add(reporter.interval).Sub(now), func() {
+				reporter.flush(signature)
+			})
+		}
+
+		return false, 0
+	}
+
+	suppressed := report.suppressed
+	report.sentAt = now
+	report.suppressed = 0
+	report.scheduled = false
+
+	return true, suppressed
+}
+
+// flush sends number of suppressed reports with signature, if interval is over and no report was sent since.
+func (reporter *ErrorReporter) flush(signature string) {
+	reporter.lock.Lock()
+
+	report, ok := reporter.reports[signature]
+	if !ok || report.suppressed == 0 || reporter.now().Sub(report.sentAt) < reporter.interval {
+		reporter.lock.Unlock()
+		return
+	}
+
+	suppressed, client := report.suppressed, report.client
+	delete(reporter.reports, signature)
+
+	reporter.lock.Unlock()
+
+	pm := tg.HTML
+
+	reporter.send(context.Background(), client, pm.Text(
+		pm.Line(pm.Bold("Error"), pm.Escape(fmt.Sprintf("repeated %d more times", suppressed))),
+		pm.Pre(pm.Escape(truncateString(signature, 1024))),
+	), signature)
+}
+
+// send sends report with context detached from ctx and limited by timeout.
+func (reporter *ErrorReporter) send(ctx context.Context, client *tg.Client, text string, about string) {
+	if client == nil {
+		reporter.log("no client to send report about error: %v", about)
+		return
+	}
+
+	ctx = withoutCancel(ctx)
+	if reporter.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, reporter.timeout)
+		defer cancel()
+	}
+
+	if err := client.SendMessage(reporter.chatID, text).
+		ParseMode(tg.HTML).
+		LinkPreviewOptions(tg.LinkPreviewOptions{IsDisabled: true}).
+		DoVoid(ctx); err != nil {
+		reporter.log("send report about error %q: %v", about, err)
+	}
+}
+
+// Handle reports the error to the chat. It implements [ErrorHandler].
+func (reporter *ErrorReporter) Handle(ctx context.Context, update *Update, err error) error {
+	client := reporter.client
+	if client == nil {
+		client = update.Client
+	}
+
+	if ok, suppressed := reporter.allow(reporter.signatureFunc(err), client); ok {
+		reporter.send(ctx, client, reporter.format(update, err, suppressed), err.Error())
+	}
+
+	if reporter.next != nil {
+		return reporter.next(ctx, update, err)
+	}
+
+	return nil
+}
+
+func (reporter *ErrorReporter) format(update *Update, err error, suppressed int) string {
+	pm := tg.HTML
+
+	lines := []string{
+		pm.Line(pm.Bold("Error"), "handling update", pm.Code(strconv.Itoa(update.ID)), pm.Escape("("+update.Type().String()+")")),
+	}
+
+	if chat := update.Chat(); chat != nil {
+		line := []string{"Chat:", pm.Code(strconv.FormatInt(int64(chat.ID), 10))}
+		if chat.Title != "" {
+			line = append(line, pm.Escape(chat.Title))
+		}
+		if chat.Username != "" {
+			line = append(line, pm.Escape(chat.Username.PeerID()))
+		}
+		lines = append(lines, pm.Line(line...))
+	}
+
+	if user := getUpdateUser(update); user != nil {
+		line := []string{"User:", pm.Code(strconv.FormatInt(int64(user.ID), 10)), pm.Escape(strings.TrimSpace(user.FirstName + " " + user.LastName))}
+		if user.Username != "" {
+			line = append(line, pm.Escape(user.Username.PeerID()))
+		}
+		lines = append(lines, pm.Line(line...))
+	}
+
+	if suppressed > 0 {
+		lines = append(lines, pm.Italic(fmt.Sprintf("%d similar errors were suppressed", suppressed)))
+	}
+
+	lines = append(lines, "", pm.Bold("Error:"), pm.Pre(pm.Escape(truncateString(formatErrorChain(err), 1024))))
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) && reporter.maxStackLen > 0 {
+		lines = append(lines, pm.Bold("Stack:"), pm.Pre(pm.Escape(truncateString(string(panicErr.Stack), reporter.maxStackLen))))
+	}
+
+	if reporter.maxUpdateLen > 0 && update.Update != nil {
+		data, err := json.Marshal(update.Update)
+		if err == nil {
+			lines = append(lines, pm.Bold("Update:"), pm.Pre(pm.Escape(truncateString(string(data), reporter.maxUpdateLen))))
+		}
+	}
+
+	return pm.Text(lines...)
+}
+
+// formatErrorChain returns messages of wrapped errors, one per line.
+// Each line contains only message part which is not included into the wrapped error.
+func formatErrorChain(err error) string {
+	var lines []string
+
+	for err != nil {
+		msg := err.Error()
+
+		next := errors.Unwrap(err)
+		if next != nil {
+			msg = strings.TrimSuffix(strings.TrimSuffix(msg, next.Error()), ": ")
+		}
+
+		lines = append(lines, fmt.Sprintf("%T: %s", err, msg))
+
+		err = next
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// truncateString truncates s to n runes, adding ellipsis if truncated.
+func truncateString(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+
+	return string(runes[:n]) + "…"
+}
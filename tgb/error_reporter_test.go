@@ -0,0 +1,179 @@
+package tgb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReporter(t *testing.T) {
+	var (
+		texts      []string
+		parseModes []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/sendMessage":
+			assert.Equal(t, "-100", r.FormValue("chat_id"))
+			texts = append(texts, r.FormValue("text"))
+			parseModes = append(parseModes, r.FormValue("parse_mode"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":-100,"type":"supergroup"}}}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+
+	newUpdate := func(id int) *Update {
+		return &Update{
+			Client: client,
+			Update: &tg.Update{
+				ID: id,
+				Message: &tg.Message{
+					ID:   id,
+					Chat: tg.Chat{ID: 42, Type: tg.ChatTypePrivate, Username: "john"},
+					From: &tg.User{ID: 42, FirstName: "John", LastName: "<Doe>", Username: "john"},
+					Text: "hello",
+				},
+			},
+		}
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var nextCalls int
+
+	reporter := NewErrorReporter(tg.ChatID(-100),
+		WithErrorReporterInterval(time.Minute),
+		WithErrorReporterNext(func(ctx context.Context, update *Update, err error) error {
+			nextCalls++
+			return err
+		}),
+	)
+	reporter.now = func() time.Time { return now }
+
+	var flushes []func()
+	reporter.afterFunc = func(d time.Duration, f func()) {
+		assert.Equal(t, time.Minute, d)
+		flushes = append(flushes, f)
+	}
+
+	ctx := context.Background()
+	errBase := errors.New("connection refused")
+
+	t.Run("Report", func(t *testing.T) {
+		err := reporter.Handle(ctx, newUpdate(1), fmt.Errorf("save user 1: %w", errBase))
+		assert.ErrorIs(t, err, errBase, "result of next handler")
+
+		require.Len(t, texts, 1)
+		assert.Equal(t, "HTML", parseModes[0])
+
+		text := texts[0]
+		assert.Contains(t, text, "<b>Error</b> handling update <code>1</code> (message)")
+		assert.Contains(t, text, "Chat: <code>42</code> @john")
+		assert.Contains(t, text, "User: <code>42</code> John &lt;Doe&gt; @john")
+		assert.Contains(t, text, "*fmt.wrapError: save user 1\n*errors.errorString: connection refused")
+		assert.Contains(t, text, "<b>Update:</b>\n<pre>{&#34;update_id&#34;:1")
+		assert.NotContains(t, text, "Stack:")
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		texts = nil
+
+		// same signature, differ only by id
+		require.Error(t, reporter.Handle(ctx, newUpdate(2), fmt.Errorf("save user 2: %w", errBase)))
+		require.Error(t, reporter.Handle(ctx, newUpdate(3), fmt.Errorf("save user 3: %w", errBase)))
+		assert.Empty(t, texts)
+
+		// other signature
+		require.Error(t, reporter.Handle(ctx, newUpdate(4), errors.New("other")))
+		assert.Len(t, texts, 1)
+
+		now = now.Add(time.Minute)
+
+		require.Error(t, reporter.Handle(ctx, newUpdate(5), fmt.Errorf("save user 5: %w", errBase)))
+		require.Len(t, texts, 2)
+		assert.Contains(t, texts[1], "<i>2 similar errors were suppressed</i>")
+
+		assert.Equal(t, 5, nextCalls)
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		texts = nil
+
+		update := newUpdate(6)
+		err := safeHandle(ctx, HandlerFunc(func(ctx context.Context, update *Update) error {
+			panic("boom")
+		}), update)
+
+		require.Error(t, reporter.Handle(ctx, update, err))
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "*tgb.PanicError: panic: boom")
+		assert.Contains(t, texts[0], "<b>Stack:</b>\n<pre>goroutine")
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		texts = nil
+		flushes = nil
+
+		require.Error(t, reporter.Handle(ctx, newUpdate(7), errors.New("flush 1")))
+		require.Error(t, reporter.Handle(ctx, newUpdate(8), errors.New("flush 2")))
+		require.Error(t, reporter.Handle(ctx, newUpdate(9), errors.New("flush 3")))
+		require.Len(t, texts, 1)
+		require.Len(t, flushes, 1, "flush is scheduled once")
+
+		// interval is not over
+		flushes[0]()
+		require.Len(t, texts, 1)
+
+		now = now.Add(time.Minute)
+		flushes[0]()
+		require.Len(t, texts, 2)
+		assert.Equal(t, "<b>Error</b> repeated 2 more times\n<pre>flush N</pre>", texts[1])
+
+		// entry is removed after flush
+		flushes[0]()
+		require.Len(t, texts, 2)
+
+		require.Error(t, reporter.Handle(ctx, newUpdate(10), errors.New("flush 4")))
+		require.Len(t, texts, 3)
+		assert.NotContains(t, texts[2], "suppressed")
+	})
+
+	t.Run("CanceledContext", func(t *testing.T) {
+		texts = nil
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.Error(t, reporter.Handle(ctx, newUpdate(11), context.Canceled))
+		assert.Len(t, texts, 1)
+	})
+}
+
+func TestErrorSignature(t *testing.T) {
+	assert.Equal(t,
+		ErrorSignature(fmt.Errorf("user 1: %w", errors.New("chat -1001 not found"))),
+		ErrorSignature(fmt.Errorf("user 2: %w", errors.New("chat -1002 not found"))),
+	)
+
+	assert.Equal(t, "panic: index out of range [N]", ErrorSignature(&PanicError{Value: "index out of range [5]"}))
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "hello", truncateString("hello", 5))
+	assert.Equal(t, "при…", truncateString("привет", 3))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf16"
 
 	tg "github.com/nosefu/go-tg"
 	"golang.org/x/exp/slices"
@@ -40,18 +41,24 @@ func Any(filters ...Filter) Filter {
 	})
 }
 
+// allFilter is a separate type, so nested filters can be inspected, e.g. by [HasRole].
+type allFilter []Filter
+
 // All pass update to handler, if all of filters allow it.
 func All(filters ...Filter) Filter {
-	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
-		for _, filter := range filters {
-			if allow, err := filter.Allow(ctx, update); err != nil {
-				return false, err
-			} else if !allow {
-				return false, nil
-			}
+	return allFilter(filters)
+}
+
+// Allow implements Filter interface.
+func (filters allFilter) Allow(ctx context.Context, update *Update) (bool, error) {
+	for _, filter := range filters {
+		if allow, err := filter.Allow(ctx, update); err != nil {
+			return false, err
+		} else if !allow {
+			return false, nil
 		}
-		return true, nil
-	})
+	}
+	return true, nil
 }
 
 // Not pass update to handler, if specified filter does not allow it.
@@ -74,6 +81,7 @@ type commandFilter struct {
 	ignoreMention bool
 	ignoreCase    bool
 	ignoreCaption bool
+	description   string
 }
 
 type CommandFilterOption func(*commandFilter)
@@ -116,7 +124,16 @@ func WithCommandAlias(aliases ...string) CommandFilterOption {
 	}
 }
 
+// WithCommandDescription sets description of the command.
+// Commands with description are returned by [Router.Commands], so they can be passed to setMyCommands.
+func WithCommandDescription(description string) CommandFilterOption {
+	return func(filter *commandFilter) {
+		filter.description = description
+	}
+}
+
 // Command adds filter for command with specified options.
+// Parsed command is passed to handler via context, see [CommandArgsFromContext].
 func Command(command string, opts ...CommandFilterOption) Filter {
 	filter := &commandFilter{
 		commands:      []string{command},
@@ -207,6 +224,8 @@ func (filter *commandFilter) Allow(ctx context.Context, update *Update) (bool, e
 		return false, nil
 	}
 
+	setFilterValue(ctx, commandArgsContextKey, parseCommandArgs(text))
+
 	return true, nil
 }
 
@@ -246,6 +265,8 @@ func extractUpdateText(update *Update) (string, bool) {
 //   - Update.InlineQuery.Query
 //   - Update.ChosenInlineResult.Query
 //   - Update.Poll.Question
+//
+// The match is passed to handler via context, see [RegexpMatchFromContext].
 func Regexp(re *regexp.Regexp) Filter {
 	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
 		var text string
@@ -255,7 +276,14 @@ func Regexp(re *regexp.Regexp) Filter {
 			return false, nil
 		}
 
-		return re.MatchString(text), nil
+		match := newRegexpMatch(re, text)
+		if match == nil {
+			return false, nil
+		}
+
+		setFilterValue(ctx, regexpMatchContextKey, match)
+
+		return true, nil
 	})
 }
 
@@ -445,3 +473,128 @@ func TextIn(vs []string, opts ...TextFuncFilterOption) Filter {
 		return slices.Contains(vs, text)
 	}, opts...)
 }
+
+// getEntityText returns part of text covered by entity.
+// Offset and length of entity are measured in UTF-16 code units.
+func getEntityText(text string, entity tg.MessageEntity) string {
+	encoded := utf16.Encode([]rune(text))
+
+	end := entity.Offset + entity.Length
+	if entity.Offset < 0 || entity.Length < 0 || end > len(encoded) {
+		return ""
+	}
+
+	return string(utf16.Decode(encoded[entity.Offset:end]))
+}
+
+// getMessageTextEntities returns text of the message with entities, or caption if text is empty.
+func getMessageTextEntities(msg *tg.Message) (string, []tg.MessageEntity) {
+	if msg.Text != "" {
+		return msg.Text, msg.Entities
+	}
+
+	return msg.Caption, msg.CaptionEntities
+}
+
+// isBotMentioned checks mention and text_mention entities of the message.
+// If withCommand is true, commands with bot username (/start@bot) are checked too.
+func isBotMentioned(msg *tg.Message, me tg.User, withCommand bool) bool {
+	text, entities := getMessageTextEntities(msg)
+
+	for _, entity := range entities {
+		switch entity.Type {
+		case tg.MessageEntityTypeMention:
+			mention := strings.TrimPrefix(getEntityText(text, entity), "@")
+			if me.Username != "" && strings.EqualFold(mention, string(me.Username)) {
+				return true
+			}
+		case tg.MessageEntityTypeTextMention:
+			if entity.User != nil && entity.User.ID == me.ID {
+				return true
+			}
+		case tg.MessageEntityTypeBotCommand:
+			if !withCommand {
+				continue
+			}
+
+			_, mention, ok := strings.Cut(getEntityText(text, entity), "@")
+			if ok && me.Username != "" && strings.EqualFold(mention, string(me.Username)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isReplyToBot checks if message is a reply to message of the bot.
+// Messages in forum topics are replies to topic creation message, that is not considered as reply.
+func isReplyToBot(msg *tg.Message, me tg.User) bool {
+	reply := msg.ReplyToMessage
+
+	if reply == nil || reply.ForumTopicCreated != nil {
+		return false
+	}
+
+	return reply.From != nil && reply.From.ID == me.ID
+}
+
+// Mentioned checks if bot is mentioned in Message, EditedMessage, ChannelPost, EditedChannelPost text or caption.
+// Both mention (@username) and text_mention (for users without username) entities are checked.
+func Mentioned() Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		msg := getUpdateMessage(update)
+		if msg == nil {
+			return false, nil
+		}
+
+		me, err := update.Client.Me(ctx)
+		if err != nil {
+			return false, fmt.Errorf("mentioned filter: get current bot info: %w", err)
+		}
+
+		return isBotMentioned(msg, me, false), nil
+	})
+}
+
+// ReplyToBot checks if Message, EditedMessage, ChannelPost, EditedChannelPost is a reply to message of the bot.
+func ReplyToBot() Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		msg := getUpdateMessage(update)
+		if msg == nil || msg.ReplyToMessage == nil {
+			return false, nil
+		}
+
+		me, err := update.Client.Me(ctx)
+		if err != nil {
+			return false, fmt.Errorf("reply to bot filter: get current bot info: %w", err)
+		}
+
+		return isReplyToBot(msg, me), nil
+	})
+}
+
+// AddressedToBot checks if Message, EditedMessage, ChannelPost, EditedChannelPost is addressed to bot:
+//   - sent in private chat with bot;
+//   - bot is mentioned, see [Mentioned];
+//   - it's a reply to message of the bot, see [ReplyToBot];
+//   - it contains command with bot username (/start@bot).
+func AddressedToBot() Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		msg := getUpdateMessage(update)
+		if msg == nil {
+			return false, nil
+		}
+
+		if msg.Chat.Type == tg.ChatTypePrivate {
+			return true, nil
+		}
+
+		me, err := update.Client.Me(ctx)
+		if err != nil {
+			return false, fmt.Errorf("addressed to bot filter: get current bot info: %w", err)
+		}
+
+		return isBotMentioned(msg, me, true) || isReplyToBot(msg, me), nil
+	})
+}
@@ -0,0 +1,269 @@
+package tgb
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/nosefu/go-tg"
+	"golang.org/x/exp/slices"
+)
+
+// getUpdateAnyMessage returns first not nil message from update fields including business messages.
+func getUpdateAnyMessage(update *Update) *tg.Message {
+	return firstNotNil(
+		update.Message,
+		update.EditedMessage,
+		update.ChannelPost,
+		update.EditedChannelPost,
+		update.BusinessMessage,
+		update.EditedBusinessMessage,
+	)
+}
+
+// messageFilter creates filter that checks message of update with fn.
+// Message is looked up in Message, EditedMessage, ChannelPost, EditedChannelPost,
+// BusinessMessage and EditedBusinessMessage, so do all message filters built with it.
+func messageFilter(fn func(msg *tg.Message) bool) Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		msg := getUpdateAnyMessage(update)
+		if msg == nil {
+			return false, nil
+		}
+
+		return fn(msg), nil
+	})
+}
+
+// Possible values of [tg.MessageOrigin] type, see [Forwarded].
+const (
+	MessageOriginUser       = "user"
+	MessageOriginHiddenUser = "hidden_user"
+	MessageOriginChat       = "chat"
+	MessageOriginChannel    = "channel"
+)
+
+// Forwarded checks if message is forwarded.
+// If origin types are specified, it checks if message origin type is one of them,
+// see MessageOrigin... constants.
+func Forwarded(types ...string) Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		if msg.ForwardOrigin == nil {
+			return false
+		}
+
+		return len(types) == 0 || slices.Contains(types, msg.ForwardOrigin.Type())
+	})
+}
+
+// ForwardedFrom checks if message is forwarded from one of specified chats.
+// Source chat is MessageOriginChat.SenderChat or MessageOriginChannel.Chat.
+// Messages forwarded from users are matched by user id.
+func ForwardedFrom(ids ...tg.ChatID) Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		origin := msg.ForwardOrigin
+		if origin == nil {
+			return false
+		}
+
+		var id tg.ChatID
+
+		switch {
+		case origin.User != nil:
+			id = tg.ChatID(origin.User.SenderUser.ID)
+		case origin.Chat != nil:
+			id = origin.Chat.SenderChat.ID
+		case origin.Channel != nil:
+			id = origin.Channel.Chat.ID
+		default:
+			return false
+		}
+
+		return slices.Contains(ids, id)
+	})
+}
+
+// ViaBot checks if message is sent via inline bot.
+// If usernames are specified (with or without @), it checks if bot username is one of them (case insensitive).
+func ViaBot(usernames ...string) Filter {
+	usernames = slices.Clone(usernames)
+	for i, username := range usernames {
+		usernames[i] = strings.TrimPrefix(username, "@")
+	}
+
+	return messageFilter(func(msg *tg.Message) bool {
+		if msg.ViaBot == nil {
+			return false
+		}
+
+		if len(usernames) == 0 {
+			return true
+		}
+
+		for _, username := range usernames {
+			if strings.EqualFold(username, string(msg.ViaBot.Username)) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Reply checks if message is a reply.
+// If message ids are specified, it checks if message is a reply to one of them.
+// Messages in forum topics are replies to topic creation message, that is not considered as reply.
+func Reply(ids ...int) Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		reply := msg.ReplyToMessage
+		if reply == nil || reply.ForumTopicCreated != nil {
+			return false
+		}
+
+		return len(ids) == 0 || slices.Contains(ids, reply.ID)
+	})
+}
+
+// MessageThread checks if message belongs to thread or forum topic.
+// If thread ids are specified, it checks if message thread id is one of them.
+func MessageThread(ids ...int) Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		if msg.MessageThreadID == 0 {
+			return false
+		}
+
+		return len(ids) == 0 || slices.Contains(ids, msg.MessageThreadID)
+	})
+}
+
+// BusinessConnection checks if update is received on behalf of business account.
+// If connection ids are specified, it checks if business connection id is one of them.
+//
+// Check is performed in:
+//   - Message, EditedMessage, ChannelPost, EditedChannelPost, BusinessMessage, EditedBusinessMessage
+//   - BusinessConnection.ID
+//   - DeletedBusinessMessages.BusinessConnectionID
+func BusinessConnection(ids ...string) Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		var id string
+
+		msg := getUpdateAnyMessage(update)
+
+		switch {
+		case msg != nil:
+			id = msg.BusinessConnectionID
+		case update.BusinessConnection != nil:
+			id = update.BusinessConnection.ID
+		case update.DeletedBusinessMessages != nil:
+			id = update.DeletedBusinessMessages.BusinessConnectionID
+		}
+
+		if id == "" {
+			return false, nil
+		}
+
+		return len(ids) == 0 || slices.Contains(ids, id), nil
+	})
+}
+
+// Language checks if language of update sender is one of specified IETF language tags.
+// Base language matches any region, e.g. "en" matches "en" and "en-US", but "en-US" matches only "en-US".
+// Comparison is case insensitive.
+func Language(codes ...string) Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		user := getUpdateUser(update)
+		if user == nil || user.LanguageCode == "" {
+			return false, nil
+		}
+
+		for _, code := range codes {
+			if strings.EqualFold(user.LanguageCode, code) {
+				return true, nil
+			}
+
+			if len(user.LanguageCode) > len(code) &&
+				user.LanguageCode[len(code)] == '-' &&
+				strings.EqualFold(user.LanguageCode[:len(code)], code) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// Premium checks if update sender is Telegram Premium user.
+func Premium() Filter {
+	return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+		user := getUpdateUser(update)
+
+		return user != nil && user.IsPremium, nil
+	})
+}
+
+// DocumentSize checks if message contains document with file size in range [min, max] in bytes.
+// Zero max means no upper limit.
+// Document without known file size is not allowed.
+func DocumentSize(min, max int64) Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		if msg.Document == nil || msg.Document.FileSize == 0 {
+			return false
+		}
+
+		size := msg.Document.FileSize
+
+		return size >= min && (max == 0 || size <= max)
+	})
+}
+
+// DocumentMIMEType checks if message contains document with one of specified MIME types.
+// Types can contain patterns in [path.Match] syntax, e.g. "image/*".
+// Comparison is case insensitive.
+func DocumentMIMEType(types ...string) Filter {
+	types = slices.Clone(types)
+	for i, typ := range types {
+		types[i] = strings.ToLower(typ)
+	}
+
+	return messageFilter(func(msg *tg.Message) bool {
+		if msg.Document == nil || msg.Document.MIMEType == "" {
+			return false
+		}
+
+		mimeType := strings.ToLower(msg.Document.MIMEType)
+
+		for _, typ := range types {
+			if ok, _ := path.Match(typ, mimeType); ok {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// PhotoSize checks if message contains photo with dimensions
+// in range [minWidth, maxWidth] x [minHeight, maxHeight] in pixels.
+// Largest available size of the photo is checked.
+// Zero max value means no upper limit.
+func PhotoSize(minWidth, minHeight, maxWidth, maxHeight int) Filter {
+	inRange := func(v, min, max int) bool {
+		return v >= min && (max == 0 || v <= max)
+	}
+
+	return messageFilter(func(msg *tg.Message) bool {
+		if len(msg.Photo) == 0 {
+			return false
+		}
+
+		largest := msg.Photo[0]
+		for _, size := range msg.Photo[1:] {
+			if size.Width*size.Height > largest.Width*largest.Height {
+				largest = size
+			}
+		}
+
+		return inRange(largest.Width, minWidth, maxWidth) &&
+			inRange(largest.Height, minHeight, maxHeight)
+	})
+}
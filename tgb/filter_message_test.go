@@ -0,0 +1,110 @@
+package tgb
+
+import (
+	"context"
+	"testing"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageFilters(t *testing.T) {
+	channel := tg.Chat{ID: -1001, Type: tg.ChatTypeChannel}
+
+	msg := func(msg *tg.Message) *Update {
+		return &Update{Update: &tg.Update{Message: msg}}
+	}
+
+	for _, test := range []struct {
+		Name    string
+		Filter  Filter
+		Update  *Update
+		Allowed bool
+	}{
+		{"Forwarded/Any", Forwarded(), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{HiddenUser: &tg.MessageOriginHiddenUser{}}}), true},
+		{"Forwarded/NotForwarded", Forwarded(), msg(&tg.Message{}), false},
+		{"Forwarded/Type", Forwarded(MessageOriginChannel, MessageOriginChat), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{Channel: &tg.MessageOriginChannel{Chat: channel}}}), true},
+		{"Forwarded/OtherType", Forwarded(MessageOriginChannel), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{User: &tg.MessageOriginUser{}}}), false},
+		{"Forwarded/NotMessage", Forwarded(), &Update{Update: &tg.Update{CallbackQuery: &tg.CallbackQuery{}}}, false},
+
+		{"ForwardedFrom/Channel", ForwardedFrom(channel.ID), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{Channel: &tg.MessageOriginChannel{Chat: channel}}}), true},
+		{"ForwardedFrom/Chat", ForwardedFrom(channel.ID), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{Chat: &tg.MessageOriginChat{SenderChat: channel}}}), true},
+		{"ForwardedFrom/User", ForwardedFrom(10), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{User: &tg.MessageOriginUser{SenderUser: tg.User{ID: 10}}}}), true},
+		{"ForwardedFrom/Other", ForwardedFrom(-1002), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{Channel: &tg.MessageOriginChannel{Chat: channel}}}), false},
+		{"ForwardedFrom/HiddenUser", ForwardedFrom(0), msg(&tg.Message{ForwardOrigin: &tg.MessageOrigin{HiddenUser: &tg.MessageOriginHiddenUser{}}}), false},
+
+		{"ViaBot/Any", ViaBot(), msg(&tg.Message{ViaBot: &tg.User{Username: "gif"}}), true},
+		{"ViaBot/Username", ViaBot("@GIF", "vid"), msg(&tg.Message{ViaBot: &tg.User{Username: "gif"}}), true},
+		{"ViaBot/OtherUsername", ViaBot("vid"), msg(&tg.Message{ViaBot: &tg.User{Username: "gif"}}), false},
+		{"ViaBot/NotViaBot", ViaBot(), msg(&tg.Message{}), false},
+
+		{"Reply/Any", Reply(), msg(&tg.Message{ReplyToMessage: &tg.Message{ID: 1}}), true},
+		{"Reply/ID", Reply(2, 3), msg(&tg.Message{ReplyToMessage: &tg.Message{ID: 3}}), true},
+		{"Reply/OtherID", Reply(2), msg(&tg.Message{ReplyToMessage: &tg.Message{ID: 3}}), false},
+		{"Reply/ForumTopic", Reply(), msg(&tg.Message{ReplyToMessage: &tg.Message{ID: 1, ForumTopicCreated: &tg.ForumTopicCreated{}}}), false},
+		{"Reply/Edited", Reply(), &Update{Update: &tg.Update{EditedMessage: &tg.Message{ReplyToMessage: &tg.Message{ID: 1}}}}, true},
+
+		{"MessageThread/Any", MessageThread(), msg(&tg.Message{MessageThreadID: 5, IsTopicMessage: true}), true},
+		{"MessageThread/ID", MessageThread(5), msg(&tg.Message{MessageThreadID: 5}), true},
+		{"MessageThread/OtherID", MessageThread(6), msg(&tg.Message{MessageThreadID: 5}), false},
+		{"MessageThread/NoThread", MessageThread(), msg(&tg.Message{}), false},
+
+		{"BusinessConnection/Message", BusinessConnection(), &Update{Update: &tg.Update{BusinessMessage: &tg.Message{BusinessConnectionID: "abc"}}}, true},
+		{"BusinessConnection/ID", BusinessConnection("abc"), &Update{Update: &tg.Update{EditedBusinessMessage: &tg.Message{BusinessConnectionID: "abc"}}}, true},
+		{"BusinessConnection/OtherID", BusinessConnection("def"), &Update{Update: &tg.Update{BusinessMessage: &tg.Message{BusinessConnectionID: "abc"}}}, false},
+		{"BusinessConnection/Connection", BusinessConnection("abc"), &Update{Update: &tg.Update{BusinessConnection: &tg.BusinessConnection{ID: "abc"}}}, true},
+		{"BusinessConnection/Deleted", BusinessConnection("abc"), &Update{Update: &tg.Update{DeletedBusinessMessages: &tg.BusinessMessagesDeleted{BusinessConnectionID: "abc"}}}, true},
+		{"BusinessConnection/NotBusiness", BusinessConnection(), msg(&tg.Message{}), false},
+
+		{"Language/Exact", Language("en-US"), msg(&tg.Message{From: &tg.User{LanguageCode: "en-us"}}), true},
+		{"Language/Base", Language("uk", "en"), msg(&tg.Message{From: &tg.User{LanguageCode: "en-GB"}}), true},
+		{"Language/Region", Language("en-US"), msg(&tg.Message{From: &tg.User{LanguageCode: "en"}}), false},
+		{"Language/Prefix", Language("e"), msg(&tg.Message{From: &tg.User{LanguageCode: "en"}}), false},
+		{"Language/Unknown", Language("en"), msg(&tg.Message{From: &tg.User{}}), false},
+		{"Language/CallbackQuery", Language("en"), &Update{Update: &tg.Update{CallbackQuery: &tg.CallbackQuery{From: tg.User{LanguageCode: "en"}}}}, true},
+
+		{"Premium/Yes", Premium(), msg(&tg.Message{From: &tg.User{IsPremium: true}}), true},
+		{"Premium/No", Premium(), msg(&tg.Message{From: &tg.User{}}), false},
+		{"Premium/NoUser", Premium(), msg(&tg.Message{}), false},
+
+		{"DocumentSize/InRange", DocumentSize(10, 100), msg(&tg.Message{Document: &tg.Document{FileSize: 100}}), true},
+		{"DocumentSize/TooLarge", DocumentSize(10, 100), msg(&tg.Message{Document: &tg.Document{FileSize: 101}}), false},
+		{"DocumentSize/TooSmall", DocumentSize(10, 100), msg(&tg.Message{Document: &tg.Document{FileSize: 9}}), false},
+		{"DocumentSize/NoLimit", DocumentSize(0, 0), msg(&tg.Message{Document: &tg.Document{FileSize: 1 << 30}}), true},
+		{"DocumentSize/Unknown", DocumentSize(0, 100), msg(&tg.Message{Document: &tg.Document{}}), false},
+		{"DocumentSize/NoDocument", DocumentSize(0, 0), msg(&tg.Message{}), false},
+
+		{"DocumentMIMEType/Exact", DocumentMIMEType("application/pdf"), msg(&tg.Message{Document: &tg.Document{MIMEType: "application/PDF"}}), true},
+		{"DocumentMIMEType/Pattern", DocumentMIMEType("text/plain", "image/*"), msg(&tg.Message{Document: &tg.Document{MIMEType: "image/png"}}), true},
+		{"DocumentMIMEType/Other", DocumentMIMEType("image/*"), msg(&tg.Message{Document: &tg.Document{MIMEType: "application/zip"}}), false},
+		{"DocumentMIMEType/NoDocument", DocumentMIMEType("*"), msg(&tg.Message{}), false},
+
+		{"PhotoSize/InRange", PhotoSize(100, 100, 1000, 1000), msg(&tg.Message{Photo: []tg.PhotoSize{{Width: 90, Height: 60}, {Width: 800, Height: 600}}}), true},
+		{"PhotoSize/TooSmall", PhotoSize(1000, 0, 0, 0), msg(&tg.Message{Photo: []tg.PhotoSize{{Width: 90, Height: 60}, {Width: 800, Height: 600}}}), false},
+		{"PhotoSize/TooLarge", PhotoSize(0, 0, 0, 500), msg(&tg.Message{Photo: []tg.PhotoSize{{Width: 800, Height: 600}, {Width: 90, Height: 60}}}), false},
+		{"PhotoSize/NoPhoto", PhotoSize(0, 0, 0, 0), msg(&tg.Message{}), false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			allowed, err := test.Filter.Allow(context.Background(), test.Update)
+			require.NoError(t, err)
+			assert.Equal(t, test.Allowed, allowed)
+		})
+	}
+
+	t.Run("Composable", func(t *testing.T) {
+		filter := All(Forwarded(MessageOriginChannel), Not(ForwardedFrom(channel.ID)))
+
+		allowed, err := filter.Allow(context.Background(), msg(&tg.Message{
+			ForwardOrigin: &tg.MessageOrigin{Channel: &tg.MessageOriginChannel{Chat: tg.Chat{ID: -1002}}},
+		}))
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, err = filter.Allow(context.Background(), msg(&tg.Message{
+			ForwardOrigin: &tg.MessageOrigin{Channel: &tg.MessageOriginChannel{Chat: channel}},
+		}))
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+}
@@ -838,3 +838,154 @@ func TestNot(t *testing.T) {
 	assert.False(t, allow)
 	assert.Error(t, err)
 }
+
+func TestAddressedToBotFilters(t *testing.T) {
+	t.Parallel()
+
+	me := tg.User{ID: 5433024556, Username: "go_tg_test_bot"}
+
+	group := tg.Chat{ID: -100, Type: tg.ChatTypeSupergroup}
+
+	for _, test := range []struct {
+		Name      string
+		Update    *tg.Update
+		Mentioned bool
+		Reply     bool
+		Addressed bool
+	}{
+		{
+			Name:   "NotMessage",
+			Update: &tg.Update{},
+		},
+		{
+			Name: "Plain",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: group,
+				Text: "hello",
+			}},
+		},
+		{
+			Name: "Private",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: tg.Chat{ID: 1, Type: tg.ChatTypePrivate},
+				Text: "hello",
+			}},
+			Addressed: true,
+		},
+		{
+			Name: "Mention",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: group,
+				Text: "привет @Go_Tg_Test_Bot!",
+				Entities: []tg.MessageEntity{
+					{Type: tg.MessageEntityTypeMention, Offset: 7, Length: 15},
+				},
+			}},
+			Mentioned: true,
+			Addressed: true,
+		},
+		{
+			Name: "OtherMention",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: group,
+				Text: "hi @other_bot",
+				Entities: []tg.MessageEntity{
+					{Type: tg.MessageEntityTypeMention, Offset: 3, Length: 10},
+				},
+			}},
+		},
+		{
+			Name: "TextMentionInCaption",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat:    group,
+				Caption: "look, bot",
+				CaptionEntities: []tg.MessageEntity{
+					{Type: tg.MessageEntityTypeTextMention, Offset: 6, Length: 3, User: &me},
+				},
+			}},
+			Mentioned: true,
+			Addressed: true,
+		},
+		{
+			Name: "CommandWithUsername",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: group,
+				Text: "/start@go_tg_test_bot",
+				Entities: []tg.MessageEntity{
+					{Type: tg.MessageEntityTypeBotCommand, Offset: 0, Length: 21},
+				},
+			}},
+			Addressed: true,
+		},
+		{
+			Name: "CommandWithoutUsername",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: group,
+				Text: "/start",
+				Entities: []tg.MessageEntity{
+					{Type: tg.MessageEntityTypeBotCommand, Offset: 0, Length: 6},
+				},
+			}},
+		},
+		{
+			Name: "Reply",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat:           group,
+				Text:           "yes",
+				ReplyToMessage: &tg.Message{From: &me},
+			}},
+			Reply:     true,
+			Addressed: true,
+		},
+		{
+			Name: "ReplyToOther",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat:           group,
+				Text:           "yes",
+				ReplyToMessage: &tg.Message{From: &tg.User{ID: 1}},
+			}},
+		},
+		{
+			Name: "TopicCreatedByBot",
+			Update: &tg.Update{Message: &tg.Message{
+				Chat:           group,
+				Text:           "yes",
+				IsTopicMessage: true,
+				ReplyToMessage: &tg.Message{From: &me, ForumTopicCreated: &tg.ForumTopicCreated{Name: "topic"}},
+			}},
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+				update := &Update{Update: test.Update, Client: client}
+
+				for _, filter := range []struct {
+					Filter Filter
+					Allow  bool
+				}{
+					{Mentioned(), test.Mentioned},
+					{ReplyToBot(), test.Reply},
+					{AddressedToBot(), test.Addressed},
+				} {
+					allow, err := filter.Filter.Allow(ctx, update)
+					assert.NoError(t, err)
+					assert.Equal(t, filter.Allow, allow)
+				}
+			}, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/bot12345:secret/getMe", r.URL.Path)
+
+				_, _ = w.Write([]byte(`{
+					"ok": true,
+					"result": {
+						"id": 5433024556,
+						"is_bot": true,
+						"first_name": "go-tg: test bot",
+						"username": "go_tg_test_bot"
+					}
+				}`))
+			})
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package tgb
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/nosefu/go-tg"
+)
+
+// Link is a link found in message text, caption or inline keyboard.
+type Link struct {
+	// URL is the link as it appears in the message.
+	URL string
+
+	// Domain is normalized domain of the link: lower case, without port, trailing dot and www. prefix.
+	// It's empty for tg:// links.
+	Domain string
+
+	// Invite is true for chat invite links, e.g. t.me/+AbCd, t.me/joinchat/AbCd or tg://join?invite=AbCd.
+	Invite bool
+
+	// Username is set for links to public chats or users, e.g. t.me/username or tg://resolve?domain=username.
+	Username string
+}
+
+var (
+	telegramDomains = []string{"t.me", "telegram.me", "telegram.dog"}
+
+	// paths of t.me links which are not usernames.
+	telegramReservedPaths = []string{
+		"c", "addstickers", "addemoji", "addtheme", "addlist", "share", "iv",
+		"proxy", "socks", "login", "setlanguage", "boost", "bg", "invoice", "confirmphone",
+	}
+
+	usernameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{3,31}$`)
+)
+
+// normalizeDomain returns domain in lower case, without port, trailing dot and www. prefix.
+func normalizeDomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(host)
+	host = strings.TrimSuffix(host, ".")
+	host = strings.TrimPrefix(host, "www.")
+
+	return host
+}
+
+// matchDomain checks if domain matches pattern.
+// Pattern can contain wildcards in [path.Match] syntax, e.g. "*.example.com".
+// Pattern "*.example.com" matches example.com itself too.
+func matchDomain(pattern, domain string) bool {
+	pattern = normalizeDomain(pattern)
+
+	if ok, _ := path.Match(pattern, domain); ok {
+		return true
+	}
+
+	if base := strings.TrimPrefix(pattern, "*."); base != pattern {
+		return base == domain
+	}
+
+	return false
+}
+
+func matchDomainAny(patterns []string, domain string) bool {
+	for _, pattern := range patterns {
+		if matchDomain(pattern, domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseLink parses raw link from message.
+// Links without scheme are treated as http links.
+// It returns false if link is not parsable.
+func parseLink(raw string) (Link, bool) {
+	link := Link{URL: raw}
+
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return link, false
+	}
+
+	if strings.EqualFold(u.Scheme, "tg") {
+		query := u.Query()
+
+		switch strings.ToLower(u.Host) {
+		case "join":
+			link.Invite = query.Get("invite") != ""
+		case "resolve":
+			link.Username = query.Get("domain")
+		}
+
+		return link, true
+	}
+
+	link.Domain = normalizeDomain(u.Host)
+	if link.Domain == "" {
+		return link, false
+	}
+
+	for _, domain := range telegramDomains {
+		if link.Domain == domain {
+			link.Invite, link.Username = parseTelegramPath(u.Path)
+			break
+		}
+
+		// username.t.me
+		if username := strings.TrimSuffix(link.Domain, "."+domain); username != link.Domain {
+			if usernameRegexp.MatchString(username) {
+				link.Username = username
+			}
+			break
+		}
+	}
+
+	return link, true
+}
+
+// parseTelegramPath parses path of t.me link.
+func parseTelegramPath(p string) (invite bool, username string) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+
+	first := segments[0]
+
+	switch {
+	case strings.HasPrefix(first, "+"):
+		// t.me/+79991234567 is a link to phone number
+		return strings.Trim(first, "+0123456789") != "", ""
+	case first == "joinchat":
+		return len(segments) > 1 && segments[1] != "", ""
+	case first == "s" && len(segments) > 1:
+		first = segments[1]
+	}
+
+	for _, reserved := range telegramReservedPaths {
+		if strings.EqualFold(first, reserved) {
+			return false, ""
+		}
+	}
+
+	if usernameRegexp.MatchString(first) {
+		return false, first
+	}
+
+	return false, ""
+}
+
+// MessageLinks returns links from url and text_link entities of message text or caption
+// and from url buttons of message inline keyboard.
+// Links which are not parsable are skipped.
+func MessageLinks(msg *tg.Message) []Link {
+	var links []Link
+
+	add := func(raw string) {
+		if link, ok := parseLink(raw); ok {
+			links = append(links, link)
+		}
+	}
+
+	text, entities := getMessageTextEntities(msg)
+
+	for _, entity := range entities {
+		switch entity.Type {
+		case tg.MessageEntityTypeURL:
+			add(getEntityText(text, entity))
+		case tg.MessageEntityTypeTextLink:
+			add(entity.URL)
+		}
+	}
+
+	if msg.ReplyMarkup != nil {
+		for _, row := range msg.ReplyMarkup.InlineKeyboard {
+			for _, button := range row {
+				if button.URL != "" {
+					add(button.URL)
+				}
+			}
+		}
+	}
+
+	return links
+}
+
+// MessageMentions returns usernames (without @) mentioned in message text or caption
+// via mention entities or links to public chats, see [Link].Username.
+func MessageMentions(msg *tg.Message) []string {
+	var usernames []string
+
+	text, entities := getMessageTextEntities(msg)
+
+	for _, entity := range entities {
+		if entity.Type == tg.MessageEntityTypeMention {
+			usernames = append(usernames, strings.TrimPrefix(getEntityText(text, entity), "@"))
+		}
+	}
+
+	for _, link := range MessageLinks(msg) {
+		if link.Username != "" {
+			usernames = append(usernames, link.Username)
+		}
+	}
+
+	return usernames
+}
+
+type linkFilter struct {
+	allow []string
+	deny  []string
+}
+
+// LinkFilterOption is a filter option for [Links] filter.
+type LinkFilterOption func(*linkFilter)
+
+// WithLinkAllow sets domains which are allowed.
+// If allow list is set, links to other domains are matched by filter.
+// Domains can contain wildcards, e.g. "*.example.com" matches example.com and all subdomains.
+func WithLinkAllow(domains ...string) LinkFilterOption {
+	return func(filter *linkFilter) {
+		filter.allow = append(filter.allow, domains...)
+	}
+}
+
+// WithLinkDeny sets domains which are denied.
+// Deny list takes precedence over allow list.
+// Domains can contain wildcards, e.g. "*.example.com" matches example.com and all subdomains.
+func WithLinkDeny(domains ...string) LinkFilterOption {
+	return func(filter *linkFilter) {
+		filter.deny = append(filter.deny, domains...)
+	}
+}
+
+// denied checks if link is denied by filter.
+func (filter *linkFilter) denied(link Link) bool {
+	if link.Domain == "" {
+		return len(filter.allow) > 0 || len(filter.deny) == 0
+	}
+
+	if matchDomainAny(filter.deny, link.Domain) {
+		return true
+	}
+
+	if len(filter.allow) > 0 {
+		return !matchDomainAny(filter.allow, link.Domain)
+	}
+
+	return len(filter.deny) == 0
+}
+
+// Allow implements Filter interface.
+func (filter *linkFilter) Allow(ctx context.Context, update *Update) (bool, error) {
+	msg := getUpdateAnyMessage(update)
+	if msg == nil {
+		return false, nil
+	}
+
+	for _, link := range MessageLinks(msg) {
+		if filter.denied(link) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Links checks if message contains links, see [MessageLinks].
+// Without options any link is matched.
+// Use [WithLinkAllow] and [WithLinkDeny] to match only links to not allowed or denied domains.
+//
+//	// matches messages with links to any domain except example.com and its subdomains
+//	tgb.Links(tgb.WithLinkAllow("*.example.com"))
+func Links(opts ...LinkFilterOption) Filter {
+	filter := &linkFilter{}
+
+	for _, opt := range opts {
+		opt(filter)
+	}
+
+	return filter
+}
+
+// InviteLink checks if message contains chat invite links, see [Link].Invite.
+func InviteLink() Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		for _, link := range MessageLinks(msg) {
+			if link.Invite {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// ExternalMention checks if message mentions public chats or users by username, see [MessageMentions].
+// Mentions of the chat itself, sender chat and specified usernames are ignored.
+// Comparison is case insensitive.
+func ExternalMention(allowed ...string) Filter {
+	return messageFilter(func(msg *tg.Message) bool {
+		ignored := append([]string{string(msg.Chat.Username)}, allowed...)
+		if msg.SenderChat != nil {
+			ignored = append(ignored, string(msg.SenderChat.Username))
+		}
+
+	mentions:
+		for _, username := range MessageMentions(msg) {
+			for _, v := range ignored {
+				if v != "" && strings.EqualFold(strings.TrimPrefix(v, "@"), username) {
+					continue mentions
+				}
+			}
+
+			return true
+		}
+
+		return false
+	})
+}
@@ -0,0 +1,128 @@
+package tgb
+
+import (
+	"context"
+	"testing"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLink(t *testing.T) {
+	for _, test := range []struct {
+		Raw  string
+		Link Link
+	}{
+		{"https://WWW.Example.COM:8080/path?q=1", Link{Domain: "example.com"}},
+		{"example.com.", Link{Domain: "example.com"}},
+		{"t.me/+AbCdEf123", Link{Domain: "t.me", Invite: true}},
+		{"https://t.me/+79991234567", Link{Domain: "t.me"}},
+		{"https://telegram.me/joinchat/AbCdEf", Link{Domain: "telegram.me", Invite: true}},
+		{"https://t.me/joinchat", Link{Domain: "t.me"}},
+		{"https://t.me/spam_channel/123", Link{Domain: "t.me", Username: "spam_channel"}},
+		{"https://t.me/s/spam_channel", Link{Domain: "t.me", Username: "spam_channel"}},
+		{"https://t.me/addstickers/pack", Link{Domain: "t.me"}},
+		{"https://t.me/c/123456/7", Link{Domain: "t.me"}},
+		{"https://spam_channel.t.me", Link{Domain: "spam_channel.t.me", Username: "spam_channel"}},
+		{"tg://join?invite=AbCdEf", Link{Invite: true}},
+		{"tg://resolve?domain=spam_channel", Link{Username: "spam_channel"}},
+	} {
+		link, ok := parseLink(test.Raw)
+		require.True(t, ok, test.Raw)
+
+		test.Link.URL = test.Raw
+		assert.Equal(t, test.Link, link, test.Raw)
+	}
+
+	_, ok := parseLink("http://%zz")
+	assert.False(t, ok)
+}
+
+func TestMatchDomain(t *testing.T) {
+	for _, test := range []struct {
+		Pattern string
+		Domain  string
+		Match   bool
+	}{
+		{"example.com", "example.com", true},
+		{"WWW.Example.com", "example.com", true},
+		{"example.com", "sub.example.com", false},
+		{"*.example.com", "sub.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", true},
+		{"*.example.com", "notexample.com", false},
+		{"example.*", "example.org", true},
+	} {
+		assert.Equal(t, test.Match, matchDomain(test.Pattern, test.Domain), "%s %s", test.Pattern, test.Domain)
+	}
+}
+
+func TestMessageLinks(t *testing.T) {
+	msg := &tg.Message{
+		Text: "🔥 go example.com and @spam_channel, click here",
+		Entities: []tg.MessageEntity{
+			{Type: tg.MessageEntityTypeURL, Offset: 6, Length: 11},
+			{Type: tg.MessageEntityTypeMention, Offset: 22, Length: 13},
+			{Type: tg.MessageEntityTypeTextLink, Offset: 37, Length: 10, URL: "https://t.me/+AbCdEf"},
+		},
+		ReplyMarkup: &tg.InlineKeyboardMarkup{InlineKeyboard: [][]tg.InlineKeyboardButton{
+			{{Text: "Buy", URL: "https://shop.example.org"}, {Text: "Callback", CallbackData: "cb"}},
+		}},
+	}
+
+	assert.Equal(t, []Link{
+		{URL: "example.com", Domain: "example.com"},
+		{URL: "https://t.me/+AbCdEf", Domain: "t.me", Invite: true},
+		{URL: "https://shop.example.org", Domain: "shop.example.org"},
+	}, MessageLinks(msg))
+
+	assert.Equal(t, []string{"spam_channel"}, MessageMentions(msg))
+}
+
+func TestLinkFilters(t *testing.T) {
+	newUpdate := func(links ...string) *Update {
+		msg := &tg.Message{Chat: tg.Chat{ID: -100, Username: "our_chat"}, Text: "links"}
+
+		for _, link := range links {
+			msg.Entities = append(msg.Entities, tg.MessageEntity{
+				Type: tg.MessageEntityTypeTextLink,
+				URL:  link,
+			})
+		}
+
+		return &Update{Update: &tg.Update{Message: msg}}
+	}
+
+	for _, test := range []struct {
+		Name    string
+		Filter  Filter
+		Update  *Update
+		Allowed bool
+	}{
+		{"Links/Any", Links(), newUpdate("https://example.com"), true},
+		{"Links/TG", Links(), newUpdate("tg://resolve?domain=channel"), true},
+		{"Links/None", Links(), newUpdate(), false},
+		{"Links/NotMessage", Links(), &Update{Update: &tg.Update{CallbackQuery: &tg.CallbackQuery{}}}, false},
+		{"Links/Allowed", Links(WithLinkAllow("*.example.com", "t.me")), newUpdate("https://docs.example.com", "https://t.me/our_chat"), false},
+		{"Links/NotAllowed", Links(WithLinkAllow("*.example.com")), newUpdate("https://docs.example.com", "https://spam.org"), true},
+		{"Links/AllowedTG", Links(WithLinkAllow("*.example.com")), newUpdate("tg://join?invite=abc"), true},
+		{"Links/Denied", Links(WithLinkDeny("*.spam.org")), newUpdate("https://example.com", "https://www.spam.org/x"), true},
+		{"Links/NotDenied", Links(WithLinkDeny("*.spam.org")), newUpdate("https://example.com", "tg://join?invite=abc"), false},
+		{"Links/DenyPrecedence", Links(WithLinkAllow("*.example.com"), WithLinkDeny("ads.example.com")), newUpdate("https://ads.example.com"), true},
+
+		{"InviteLink/Yes", InviteLink(), newUpdate("https://example.com", "https://t.me/joinchat/AbCd"), true},
+		{"InviteLink/No", InviteLink(), newUpdate("https://t.me/channel"), false},
+
+		{"ExternalMention/Link", ExternalMention(), newUpdate("https://t.me/spam_channel"), true},
+		{"ExternalMention/OwnChat", ExternalMention(), newUpdate("https://t.me/Our_Chat"), false},
+		{"ExternalMention/Allowed", ExternalMention("@partner_channel"), newUpdate("tg://resolve?domain=partner_channel"), false},
+		{"ExternalMention/None", ExternalMention(), newUpdate("https://example.com"), false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			allowed, err := test.Filter.Allow(context.Background(), test.Update)
+			require.NoError(t, err)
+			assert.Equal(t, test.Allowed, allowed)
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
 	"sync"
 	"time"
 
@@ -18,9 +21,22 @@ type Poller struct {
 	handlerTimeout time.Duration
 	timeout        time.Duration
 	retryAfter     time.Duration
+	maxRetryAfter  time.Duration
 	limit          int
 	allowedUpdates []tg.UpdateType
 
+	offsetStore OffsetStore
+
+	ack           bool
+	ackRetryLimit int
+
+	onError func(ctx context.Context, err error) error
+	onStart func(ctx context.Context)
+	onBatch func(ctx context.Context, updates []tg.Update)
+	onStop  func(ctx context.Context, err error)
+
+	timeAfter func(time.Duration) <-chan time.Time
+
 	wg sync.WaitGroup
 }
 
@@ -40,13 +56,22 @@ func WithPollerTimeout(timeout time.Duration) PollerOption {
 	}
 }
 
-// WithPollerRetryAfter sets the retry after for polling.
+// WithPollerRetryAfter sets the initial delay before retry of failed getUpdates call.
+// Delay is doubled after each consecutive fail up to [WithPollerMaxRetryAfter].
 func WithPollerRetryAfter(retryAfter time.Duration) PollerOption {
 	return func(poller *Poller) {
 		poller.retryAfter = retryAfter
 	}
 }
 
+// WithPollerMaxRetryAfter sets the maximum delay before retry of failed getUpdates call.
+// By default is 1 minute.
+func WithPollerMaxRetryAfter(maxRetryAfter time.Duration) PollerOption {
+	return func(poller *Poller) {
+		poller.maxRetryAfter = maxRetryAfter
+	}
+}
+
 // WithPollerLimit sets the limit for batch size.
 func WithPollerLimit(limit int) PollerOption {
 	return func(poller *Poller) {
@@ -55,8 +80,14 @@ func WithPollerLimit(limit int) PollerOption {
 }
 
 // WithPollerAllowedUpdates sets the allowed updates.
+// By default, if handler is [Router], update types with registered handlers are requested,
+// see [Router.AllowedUpdates]. Otherwise Telegram defaults are used.
 func WithPollerAllowedUpdates(allowedUpdates ...tg.UpdateType) PollerOption {
 	return func(poller *Poller) {
+		if allowedUpdates == nil {
+			allowedUpdates = []tg.UpdateType{}
+		}
+
 		poller.allowedUpdates = allowedUpdates
 	}
 }
@@ -68,6 +99,69 @@ func WithPollerLogger(logger Logger) PollerOption {
 	}
 }
 
+// WithPollerOffsetStore sets the store for persisting update offset between restarts.
+// By default offset is kept in memory only.
+func WithPollerOffsetStore(store OffsetStore) PollerOption {
+	return func(poller *Poller) {
+		poller.offsetStore = store
+	}
+}
+
+// WithPollerAck enables acknowledgement mode.
+// In this mode each batch of updates is handled before the next one is requested,
+// and offset is committed only after all updates up to it was handled
+// without error or reach retryLimit attempts.
+// Retries are delayed by the value passed to [WithPollerRetryAfter].
+// Use it with [WithPollerOffsetStore] for at-least-once delivery.
+func WithPollerAck(retryLimit int) PollerOption {
+	return func(poller *Poller) {
+		poller.ack = true
+		poller.ackRetryLimit = retryLimit
+	}
+}
+
+// WithPollerOnError sets the hook which is called on each getUpdates error.
+// Error passed to hook is classified, so [ErrPollerConflict] and [ErrPollerUnauthorized] can be checked with errors.Is.
+// If hook returns nil, poller retries the call, otherwise poller stops with returned error.
+// By default poller stops on [ErrPollerConflict] and [ErrPollerUnauthorized] and retries other errors.
+func WithPollerOnError(onError func(ctx context.Context, err error) error) PollerOption {
+	return func(poller *Poller) {
+		poller.onError = onError
+	}
+}
+
+// WithPollerOnStart sets the hook which is called when poller is started.
+func WithPollerOnStart(onStart func(ctx context.Context)) PollerOption {
+	return func(poller *Poller) {
+		poller.onStart = onStart
+	}
+}
+
+// WithPollerOnBatch sets the hook which is called on each received non-empty batch of updates,
+// before they are passed to handler.
+func WithPollerOnBatch(onBatch func(ctx context.Context, updates []tg.Update)) PollerOption {
+	return func(poller *Poller) {
+		poller.onBatch = onBatch
+	}
+}
+
+// WithPollerOnStop sets the hook which is called when poller is stopped
+// and all handlers are finished. err is the error returned by poller, if any.
+func WithPollerOnStop(onStop func(ctx context.Context, err error)) PollerOption {
+	return func(poller *Poller) {
+		poller.onStop = onStop
+	}
+}
+
+var (
+	// ErrPollerConflict is returned when getUpdates is terminated by other getUpdates request.
+	// Usually it means that other instance of the bot is running with the same token.
+	ErrPollerConflict = errors.New("poller conflict: terminated by other getUpdates request")
+
+	// ErrPollerUnauthorized is returned when bot token is invalid.
+	ErrPollerUnauthorized = errors.New("poller unauthorized: invalid bot token")
+)
+
 const defaultPollerLimit = 100
 
 func NewPoller(handler Handler, client *tg.Client, opts ...PollerOption) *Poller {
@@ -75,12 +169,13 @@ func NewPoller(handler Handler, client *tg.Client, opts ...PollerOption) *Poller
 		client:  client,
 		handler: handler,
 
-		timeout:    time.Second * 5,
-		retryAfter: time.Second * 5,
-
-		allowedUpdates: []tg.UpdateType{},
+		timeout:       time.Second * 5,
+		retryAfter:    time.Second * 5,
+		maxRetryAfter: time.Minute,
 
 		limit: defaultPollerLimit,
+
+		timeAfter: time.After,
 	}
 
 	for _, opt := range opts {
@@ -90,6 +185,18 @@ func NewPoller(handler Handler, client *tg.Client, opts ...PollerOption) *Poller
 	return poller
 }
 
+func (poller *Poller) getAllowedUpdates() []tg.UpdateType {
+	if poller.allowedUpdates != nil {
+		return poller.allowedUpdates
+	}
+
+	if provider, ok := poller.handler.(allowedUpdatesProvider); ok {
+		return provider.AllowedUpdates()
+	}
+
+	return []tg.UpdateType{}
+}
+
 func (poller *Poller) log(format string, args ...interface{}) {
 	if poller.logger != nil {
 		poller.logger.Printf("tgb.Poller: "+format, args...)
@@ -112,6 +219,26 @@ func (poller *Poller) removeWebhookIfSet(ctx context.Context) error {
 	return nil
 }
 
+func (poller *Poller) handleUpdate(ctx context.Context, update *tg.Update) error {
+	if poller.handlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, poller.handlerTimeout)
+		defer cancel()
+	}
+
+	err := safeHandle(ctx, poller.handler, &Update{
+		Update: update,
+		Client: poller.client,
+	})
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		poller.log("handler panic on update %d: %v\n%s", update.ID, panicErr.Value, panicErr.Stack)
+	}
+
+	return err
+}
+
 func (poller *Poller) processUpdates(ctx context.Context, updates []tg.Update) {
 	for i := range updates {
 		poller.wg.Add(1)
@@ -119,38 +246,200 @@ func (poller *Poller) processUpdates(ctx context.Context, updates []tg.Update) {
 		go func(i int) {
 			defer poller.wg.Done()
 
-			if poller.handlerTimeout > 0 {
-				var cancel context.CancelFunc
-				ctx, cancel = context.WithTimeout(ctx, poller.handlerTimeout)
-				defer cancel()
+			if err := poller.handleUpdate(ctx, &updates[i]); err != nil {
+				poller.log("error handling update: %v", err)
 			}
+		}(i)
+	}
+}
 
-			update := &updates[i]
+// handleUpdateAck handles update with up to retryLimit attempts.
+// Returns true if update is acknowledged: handled without error or reach retry limit.
+// Error of the last attempt is returned if retry limit is reached.
+func (poller *Poller) handleUpdateAck(ctx context.Context, update *tg.Update, retryLimit int) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		err := poller.handleUpdate(ctx, update)
+		if err == nil {
+			return true, nil
+		}
 
-			err := poller.handler.Handle(ctx, &Update{
-				Update: update,
-				Client: poller.client,
-			})
+		if ctx.Err() != nil {
+			poller.log("update %d is not acknowledged due to shutdown: %v", update.ID, err)
+			return false, err
+		}
 
-			if err != nil {
-				poller.log("error handling update: %v", err)
+		if attempt >= retryLimit {
+			poller.log("error handling update %d, retry limit reached: %v", update.ID, err)
+			return true, err
+		}
+
+		poller.log("error handling update %d (attempt %d), retrying in %v...: %v", update.ID, attempt, poller.retryAfter, err)
+
+		if poller.retryAfter > 0 {
+			select {
+			case <-time.After(poller.retryAfter):
+			case <-ctx.Done():
+				return false, err
 			}
+		}
+	}
+}
+
+// processUpdatesAck handles batch of updates concurrently and waits for all of them.
+// Returns offset to commit, it points to the first not acknowledged update,
+// and number of updates handled with error.
+func (poller *Poller) processUpdatesAck(ctx context.Context, offset int, updates []tg.Update, retryLimit int) (int, int) {
+	var (
+		acked  = make([]bool, len(updates))
+		failed = make([]bool, len(updates))
+		wg     sync.WaitGroup
+	)
+
+	for i := range updates {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			var err error
+			acked[i], err = poller.handleUpdateAck(ctx, &updates[i], retryLimit)
+			failed[i] = err != nil
 		}(i)
 	}
+
+	wg.Wait()
+
+	for i := range updates {
+		if !acked[i] {
+			break
+		}
+
+		offset = updates[i].ID + 1
+	}
+
+	var failedCount int
+	for _, v := range failed {
+		if v {
+			failedCount++
+		}
+	}
+
+	return offset, failedCount
 }
 
-func (poller *Poller) Run(ctx context.Context) error {
+func (poller *Poller) getOffset(ctx context.Context) (int, error) {
+	if poller.offsetStore == nil {
+		return 0, nil
+	}
+
+	return poller.offsetStore.GetOffset(ctx)
+}
+
+func (poller *Poller) commitOffset(offset int) {
+	if poller.offsetStore == nil {
+		return
+	}
+
+	// offset should be saved even if ctx is canceled
+	if err := poller.offsetStore.SetOffset(context.Background(), offset); err != nil {
+		poller.log("error saving offset %d: %v", offset, err)
+	}
+}
+
+// classifyPollerError wraps well-known getUpdates errors with [ErrPollerConflict] or [ErrPollerUnauthorized].
+func classifyPollerError(err error) error {
+	var tgErr *tg.Error
+	if errors.As(err, &tgErr) {
+		switch tgErr.Code {
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %s", ErrPollerConflict, err)
+		case http.StatusUnauthorized:
+			return fmt.Errorf("%w: %s", ErrPollerUnauthorized, err)
+		}
+	}
+
+	return err
+}
+
+// handleError returns non-nil error if poller should be stopped.
+func (poller *Poller) handleError(ctx context.Context, err error) error {
+	if poller.onError != nil {
+		return poller.onError(ctx, err)
+	}
+
+	if errors.Is(err, ErrPollerConflict) || errors.Is(err, ErrPollerUnauthorized) {
+		return err
+	}
+
+	return nil
+}
+
+// pollerMaxBackoffExponent limits exponent of backoff to avoid overflow.
+const pollerMaxBackoffExponent = 30
+
+// backoff returns delay before retry with specified number of consecutive fails.
+// The delay calculated as retryAfter * 2^i + random jitter, limited by maxRetryAfter.
+// If Telegram asks to wait (flood control), the delay is not less than requested.
+func (poller *Poller) backoff(fails int, err error) time.Duration {
+	if poller.retryAfter <= 0 {
+		return 0
+	}
+
+	if fails > pollerMaxBackoffExponent {
+		fails = pollerMaxBackoffExponent
+	}
+
+	factor := time.Duration(1) << fails
+
+	delay := poller.retryAfter * factor
+	if delay/factor != poller.retryAfter {
+		// overflow
+		delay = math.MaxInt64
+	}
+
+	if poller.maxRetryAfter > 0 && delay > poller.maxRetryAfter {
+		delay = poller.maxRetryAfter
+	}
+
+	if delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+	}
+
+	var tgErr *tg.Error
+	if errors.As(err, &tgErr) && tgErr.Parameters != nil && tgErr.Parameters.RetryAfterDuration() > delay {
+		delay = tgErr.Parameters.RetryAfterDuration()
+	}
+
+	return delay
+}
+
+func (poller *Poller) Run(ctx context.Context) (err error) {
 	if err := poller.removeWebhookIfSet(ctx); err != nil {
 		return fmt.Errorf("remove webhook if set: %w", err)
 	}
 
-	var offset int
+	offset, err := poller.getOffset(ctx)
+	if err != nil {
+		return fmt.Errorf("get offset: %w", err)
+	}
+
+	allowedUpdates := poller.getAllowedUpdates()
+
+	if poller.onStart != nil {
+		poller.onStart(ctx)
+	}
 
 	defer func() {
 		poller.log("shutdown...")
 		poller.wg.Wait()
+
+		if poller.onStop != nil {
+			poller.onStop(ctx, err)
+		}
 	}()
 
+	var fails int
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -161,7 +450,7 @@ func (poller *Poller) Run(ctx context.Context) error {
 				GetUpdates().
 				Offset(offset).
 				Timeout(int(poller.timeout.Seconds())).
-				AllowedUpdates(poller.allowedUpdates)
+				AllowedUpdates(allowedUpdates)
 
 			if poller.limit != defaultPollerLimit {
 				call = call.Limit(poller.limit)
@@ -170,11 +459,21 @@ func (poller *Poller) Run(ctx context.Context) error {
 			updates, err := call.Do(ctx)
 
 			if err != nil && !errors.Is(err, context.Canceled) {
-				poller.log("error '%s' when getting updates, retrying in %v...", err, poller.retryAfter)
+				err = classifyPollerError(err)
+
+				if err := poller.handleError(ctx, err); err != nil {
+					poller.log("error '%s' when getting updates, stopping...", err)
+					return err
+				}
+
+				delay := poller.backoff(fails, err)
+				fails++
 
-				if poller.retryAfter > 0 {
+				poller.log("error '%s' when getting updates, retrying in %v...", err, delay)
+
+				if delay > 0 {
 					select {
-					case <-time.After(poller.retryAfter):
+					case <-poller.timeAfter(delay):
 					case <-ctx.Done():
 						return nil
 					}
@@ -182,11 +481,112 @@ func (poller *Poller) Run(ctx context.Context) error {
 				continue
 			}
 
-			if len(updates) > 0 {
+			fails = 0
+
+			if len(updates) == 0 {
+				continue
+			}
+
+			if poller.onBatch != nil {
+				poller.onBatch(ctx, updates)
+			}
+
+			if poller.ack {
+				offset, _ = poller.processUpdatesAck(ctx, offset, updates, poller.ackRetryLimit)
+			} else {
 				offset = updates[len(updates)-1].ID + 1
 				go poller.processUpdates(ctx, updates)
 			}
+
+			poller.commitOffset(offset)
 		}
 	}
 
 }
+
+// PollerStats contains statistics of the [Poller.RunOnce] call.
+type PollerStats struct {
+	// Batches is number of received non-empty batches.
+	Batches int
+
+	// Updates is number of received updates.
+	Updates int
+
+	// Failed is number of updates handled with error.
+	Failed int
+}
+
+// RunOnce fetches all pending updates without waiting for new ones,
+// handles them and returns when no updates are left.
+// It's useful for running bot by cron or in batch jobs.
+//
+// Each batch is handled completely before the next one is requested,
+// so the final getUpdates call confirms the offset of all handled updates.
+// Updates are retried only in acknowledgement mode, see [WithPollerAck].
+func (poller *Poller) RunOnce(ctx context.Context) (stats *PollerStats, err error) {
+	stats = &PollerStats{}
+
+	if err := poller.removeWebhookIfSet(ctx); err != nil {
+		return stats, fmt.Errorf("remove webhook if set: %w", err)
+	}
+
+	offset, err := poller.getOffset(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("get offset: %w", err)
+	}
+
+	allowedUpdates := poller.getAllowedUpdates()
+
+	if poller.onStart != nil {
+		poller.onStart(ctx)
+	}
+
+	if poller.onStop != nil {
+		defer func() {
+			poller.onStop(ctx, err)
+		}()
+	}
+
+	retryLimit := 1
+	if poller.ack {
+		retryLimit = poller.ackRetryLimit
+	}
+
+	for {
+		call := poller.client.
+			GetUpdates().
+			Offset(offset).
+			Timeout(0).
+			AllowedUpdates(allowedUpdates)
+
+		if poller.limit != defaultPollerLimit {
+			call = call.Limit(poller.limit)
+		}
+
+		updates, err := call.Do(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("get updates: %w", classifyPollerError(err))
+		}
+
+		if len(updates) == 0 {
+			return stats, nil
+		}
+
+		if poller.onBatch != nil {
+			poller.onBatch(ctx, updates)
+		}
+
+		stats.Batches++
+		stats.Updates += len(updates)
+
+		var failed int
+		offset, failed = poller.processUpdatesAck(ctx, offset, updates, retryLimit)
+		stats.Failed += failed
+
+		poller.commitOffset(offset)
+
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OffsetStore define interface for Poller offset persistance.
+// Offset is the identifier of the first update to be returned by getUpdates.
+// See [OffsetStoreMemory] and [OffsetStoreFile] for built-in stores.
+type OffsetStore interface {
+	// GetOffset returns a saved offset.
+	// If the offset is not saved yet, returns 0 and nil.
+	GetOffset(ctx context.Context) (int, error)
+
+	// SetOffset saves an offset.
+	SetOffset(ctx context.Context, offset int) error
+}
+
+// OffsetStoreMemory is a memory storage for Poller offset.
+// It implements [OffsetStore] and is thread-safe.
+type OffsetStoreMemory struct {
+	offset int
+	lock   sync.Mutex
+}
+
+var _ OffsetStore = (*OffsetStoreMemory)(nil)
+
+func NewOffsetStoreMemory() *OffsetStoreMemory {
+	return &OffsetStoreMemory{}
+}
+
+func (store *OffsetStoreMemory) GetOffset(ctx context.Context) (int, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.offset, nil
+}
+
+func (store *OffsetStoreMemory) SetOffset(ctx context.Context, offset int) error {
+	store.lock.Lock()
+	store.offset = offset
+	store.lock.Unlock()
+
+	return nil
+}
+
+// OffsetStoreFile is a Poller offset store that stores offset in file.
+// File is replaced atomically on each write, so it's never left half written.
+type OffsetStoreFile struct {
+	path  string
+	perms os.FileMode
+	lock  sync.Mutex
+}
+
+var _ OffsetStore = (*OffsetStoreFile)(nil)
+
+// OffsetStoreFileOption is a function that can be passed to NewOffsetStoreFile
+// to customize the behavior of the store.
+type OffsetStoreFileOption func(*OffsetStoreFile)
+
+// WithOffsetStoreFilePerm sets the permissions of the file created by the store.
+func WithOffsetStoreFilePerm(perms os.FileMode) OffsetStoreFileOption {
+	return func(store *OffsetStoreFile) {
+		store.perms = perms
+	}
+}
+
+// NewOffsetStoreFile creates a new OffsetStoreFile.
+func NewOffsetStoreFile(path string, opts ...OffsetStoreFileOption) *OffsetStoreFile {
+	store := &OffsetStoreFile{
+		path:  path,
+		perms: 0666,
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	return store
+}
+
+// GetOffset reads offset from file.
+func (store *OffsetStoreFile) GetOffset(ctx context.Context) (int, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("read file: %w", err)
+	}
+
+	offset, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("parse offset: %w", err)
+	}
+
+	return offset, nil
+}
+
+// SetOffset writes offset to file.
+func (store *OffsetStoreFile) SetOffset(ctx context.Context, offset int) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	dir := filepath.Dir(store.path)
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("create dir if not exists: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(store.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.Itoa(offset)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	// flush data to disk before rename, otherwise rename can survive crash with empty file
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), store.perms); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), store.path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
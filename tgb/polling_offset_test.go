@@ -0,0 +1,63 @@
+package tgb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func genericOffsetStoreTest(t *testing.T, store OffsetStore) {
+	t.Helper()
+
+	offset, err := store.GetOffset(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, offset)
+
+	err = store.SetOffset(context.Background(), 123)
+	require.NoError(t, err)
+
+	offset, err = store.GetOffset(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 123, offset)
+}
+
+func TestOffsetStoreMemory(t *testing.T) {
+	genericOffsetStoreTest(t, NewOffsetStoreMemory())
+}
+
+func TestOffsetStoreFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Generic", func(t *testing.T) {
+		genericOffsetStoreTest(t, NewOffsetStoreFile(filepath.Join(dir, "generic", "offset")))
+	})
+
+	t.Run("Perms", func(t *testing.T) {
+		path := filepath.Join(dir, "offset")
+
+		store := NewOffsetStoreFile(path, WithOffsetStoreFilePerm(0600))
+
+		err := store.SetOffset(context.Background(), 42)
+		require.NoError(t, err)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "temp file should be removed")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid")
+		require.NoError(t, os.WriteFile(path, []byte("abc"), 0666))
+
+		_, err := NewOffsetStoreFile(path).GetOffset(context.Background())
+		assert.Error(t, err)
+	})
+}
@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
 	tg "github.com/nosefu/go-tg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPoller(t *testing.T) {
@@ -133,6 +135,362 @@ func TestPoller(t *testing.T) {
 	})
 }
 
+func TestPoller_AllowedUpdates(t *testing.T) {
+	router := NewRouter().
+		CallbackQuery(func(ctx context.Context, cqu *CallbackQueryUpdate) error { return nil }).
+		ChatMember(func(ctx context.Context, cmu *ChatMemberUpdatedUpdate) error { return nil })
+
+	for _, test := range []struct {
+		Name    string
+		Handler Handler
+		Options []PollerOption
+		Want    string
+	}{
+		{
+			Name:    "Router",
+			Handler: router,
+			Want:    `["callback_query","chat_member"]`,
+		},
+		{
+			Name:    "RouterExplicit",
+			Handler: router,
+			Options: []PollerOption{WithPollerAllowedUpdates()},
+			Want:    `[]`,
+		},
+		{
+			Name:    "Handler",
+			Handler: HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+			Want:    `[]`,
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			var allowedUpdates string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+
+				switch r.URL.Path {
+				case "/bot1234:secret/getWebhookInfo":
+					_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+				case "/bot1234:secret/getUpdates":
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+
+					vs, err := url.ParseQuery(string(body))
+					assert.NoError(t, err)
+
+					allowedUpdates = vs.Get("allowed_updates")
+
+					_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+				default:
+					t.Fatalf("unexcepted call '%s'", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			_, err := NewPoller(
+				test.Handler,
+				tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+				test.Options...,
+			).RunOnce(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, test.Want, allowedUpdates)
+		})
+	}
+}
+
+func TestPoller_Ack(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		offsets  []string
+		attempts = map[int]int{}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "/bot1234:secret/getUpdates":
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			vs, err := url.ParseQuery(string(body))
+			assert.NoError(t, err)
+
+			lock.Lock()
+			offsets = append(offsets, vs.Get("offset"))
+			lock.Unlock()
+
+			switch vs.Get("offset") {
+			case "10":
+				_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":10},{"update_id":11}]}`))
+			case "12":
+				cancel()
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+			default:
+				t.Errorf("unexpected offset '%s'", vs.Get("offset"))
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+			}
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOffsetStoreMemory()
+	require.NoError(t, store.SetOffset(ctx, 10))
+
+	err := NewPoller(
+		HandlerFunc(func(ctx context.Context, update *Update) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			attempts[update.ID]++
+
+			// update 10 succeeds on second attempt, update 11 always fails
+			if update.ID == 11 || attempts[update.ID] == 1 {
+				return assert.AnError
+			}
+
+			return nil
+		}),
+		tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+		WithPollerRetryAfter(time.Millisecond),
+		WithPollerOffsetStore(store),
+		WithPollerAck(3),
+	).Run(ctx)
+	require.NoError(t, err)
+
+	offset, err := store.GetOffset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 12, offset)
+
+	assert.Equal(t, 2, attempts[10])
+	assert.Equal(t, 3, attempts[11])
+	assert.Equal(t, []string{"10", "12"}, offsets)
+}
+
+func TestPoller_RunOnce(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		offsets []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "/bot1234:secret/getUpdates":
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			vs, err := url.ParseQuery(string(body))
+			assert.NoError(t, err)
+
+			assert.Equal(t, "0", vs.Get("timeout"))
+
+			lock.Lock()
+			offsets = append(offsets, vs.Get("offset"))
+			lock.Unlock()
+
+			switch vs.Get("offset") {
+			case "5":
+				_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":5},{"update_id":6}]}`))
+			case "7":
+				_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":7}]}`))
+			default:
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+			}
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOffsetStoreMemory()
+	require.NoError(t, store.SetOffset(context.Background(), 5))
+
+	stats, err := NewPoller(
+		HandlerFunc(func(ctx context.Context, update *Update) error {
+			if update.ID == 6 {
+				return assert.AnError
+			}
+			return nil
+		}),
+		tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+		WithPollerOffsetStore(store),
+	).RunOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, &PollerStats{Batches: 2, Updates: 3, Failed: 1}, stats)
+	assert.Equal(t, []string{"5", "7", "8"}, offsets)
+
+	offset, err := store.GetOffset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 8, offset)
+}
+
+func TestPoller_Errors(t *testing.T) {
+	newServer := func(t *testing.T, getUpdates func(w http.ResponseWriter)) *httptest.Server {
+		t.Helper()
+
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			switch r.URL.Path {
+			case "/bot1234:secret/getWebhookInfo":
+				_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+			case "/bot1234:secret/getUpdates":
+				getUpdates(w)
+			default:
+				t.Fatalf("unexcepted call '%s'", r.URL.Path)
+			}
+		}))
+	}
+
+	handler := HandlerFunc(func(ctx context.Context, update *Update) error { return nil })
+
+	for _, test := range []struct {
+		Name string
+		Code int
+		Body string
+		Err  error
+	}{
+		{
+			Name: "Conflict",
+			Code: http.StatusConflict,
+			Body: `{"ok":false,"error_code":409,"description":"Conflict: terminated by other getUpdates request; make sure that only one bot instance is running"}`,
+			Err:  ErrPollerConflict,
+		},
+		{
+			Name: "Unauthorized",
+			Code: http.StatusUnauthorized,
+			Body: `{"ok":false,"error_code":401,"description":"Unauthorized"}`,
+			Err:  ErrPollerUnauthorized,
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			server := newServer(t, func(w http.ResponseWriter) {
+				w.WriteHeader(test.Code)
+				_, _ = w.Write([]byte(test.Body))
+			})
+			defer server.Close()
+
+			var (
+				isStarted bool
+				stopErr   error
+			)
+
+			err := NewPoller(
+				handler,
+				tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+				WithPollerOnStart(func(ctx context.Context) {
+					isStarted = true
+				}),
+				WithPollerOnStop(func(ctx context.Context, err error) {
+					stopErr = err
+				}),
+			).Run(context.Background())
+
+			assert.ErrorIs(t, err, test.Err)
+			assert.True(t, isStarted)
+			assert.ErrorIs(t, stopErr, test.Err)
+		})
+	}
+
+	t.Run("OnError", func(t *testing.T) {
+		var calls int
+
+		server := newServer(t, func(w http.ResponseWriter) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":409,"description":"Conflict"}`))
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":1}]}`))
+		})
+		defer server.Close()
+
+		var (
+			errs    []error
+			batches int
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		err := NewPoller(
+			handler,
+			tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+			WithPollerRetryAfter(time.Millisecond),
+			WithPollerOnError(func(ctx context.Context, err error) error {
+				errs = append(errs, err)
+				return nil
+			}),
+			WithPollerOnBatch(func(ctx context.Context, updates []tg.Update) {
+				batches++
+				assert.Len(t, updates, 1)
+				cancel()
+			}),
+		).Run(ctx)
+
+		assert.NoError(t, err)
+		assert.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[0], ErrPollerConflict)
+		assert.Equal(t, 1, batches)
+	})
+}
+
+func TestPoller_backoff(t *testing.T) {
+	poller := NewPoller(
+		HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+		&tg.Client{},
+		WithPollerRetryAfter(time.Second),
+		WithPollerMaxRetryAfter(time.Second*10),
+	)
+
+	for fails, max := range []time.Duration{
+		time.Second,
+		time.Second * 2,
+		time.Second * 4,
+		time.Second * 8,
+		time.Second * 10,
+		time.Second * 10,
+	} {
+		delay := poller.backoff(fails, assert.AnError)
+		assert.GreaterOrEqual(t, delay, max/2, "fails %d", fails)
+		assert.Less(t, delay, max, "fails %d", fails)
+	}
+
+	delay := poller.backoff(0, &tg.Error{
+		Code:       http.StatusTooManyRequests,
+		Parameters: &tg.ResponseParameters{RetryAfter: 30},
+	})
+	assert.Equal(t, time.Second*30, delay)
+
+	delay = NewPoller(nil, &tg.Client{}, WithPollerRetryAfter(0)).backoff(3, assert.AnError)
+	assert.Zero(t, delay)
+
+	uncapped := NewPoller(nil, &tg.Client{}, WithPollerRetryAfter(time.Second), WithPollerMaxRetryAfter(0))
+	for _, fails := range []int{30, 40, 64, 1000} {
+		assert.Greater(t, uncapped.backoff(fails, assert.AnError), time.Hour, "fails %d", fails)
+	}
+}
+
 func TestPolling_log(t *testing.T) {
 	t.Run("Default", func(t *testing.T) {
 		poller := NewPoller(
@@ -0,0 +1,104 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned when handler panics.
+// It contains the recovered value, stack trace and the Update which caused the panic.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the goroutine at the moment of panic.
+	Stack []byte
+
+	// Update is the Update which caused the panic.
+	Update *Update
+}
+
+func newPanicError(v any, update *Update) *PanicError {
+	return &PanicError{
+		Value:  v,
+		Stack:  debug.Stack(),
+		Update: update,
+	}
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+// Unwrap returns the panic value if it's an error.
+func (err *PanicError) Unwrap() error {
+	if v, ok := err.Value.(error); ok {
+		return v
+	}
+	return nil
+}
+
+// RecoverOption is an option for [Recover] middleware.
+type RecoverOption func(*recoverer)
+
+type recoverer struct {
+	hook func(ctx context.Context, err *PanicError)
+}
+
+// WithRecoverHook sets function which is called on each recovered panic,
+// before the error is passed to the error handler.
+// It can be used to notify user that something went wrong, e.g. via [Update.Reply].
+func WithRecoverHook(hook func(ctx context.Context, err *PanicError)) RecoverOption {
+	return func(rec *recoverer) {
+		rec.hook = hook
+	}
+}
+
+// Recover creates middleware that recovers panics in handlers and next global middlewares.
+// Panic is converted to [PanicError] and passed to the error handler of Router (see [Router.Error]).
+// Mounted sub-routers handle panics with own error handlers.
+//
+// Poller and Webhook recover panics too, but only log them.
+func Recover(opts ...RecoverOption) GlobalMiddlewareFunc {
+	rec := &recoverer{}
+
+	for _, opt := range opts {
+		opt(rec)
+	}
+
+	return func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+		return context.WithValue(ctx, recoverContextKey, rec), update, nil
+	}
+}
+
+func recovererFromContext(ctx context.Context) *recoverer {
+	rec, _ := ctx.Value(recoverContextKey).(*recoverer)
+	return rec
+}
+
+// handlePanic converts recovered value to PanicError, calls hook and error handler.
+func (bot *Router) handlePanic(ctx context.Context, update *Update, rec *recoverer, v any) error {
+	err := newPanicError(v, update)
+
+	if rec.hook != nil {
+		rec.hook(ctx, err)
+	}
+
+	if bot.errorHandler != nil {
+		return bot.errorHandler(ctx, update, err)
+	}
+
+	return err
+}
+
+// safeHandle calls handler and converts panic to PanicError.
+func safeHandle(ctx context.Context, handler Handler, update *Update) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = newPanicError(v, update)
+		}
+	}()
+
+	return handler.Handle(ctx, update)
+}
@@ -0,0 +1,135 @@
+package tgb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+
+	newUpdate := func() *Update {
+		return &Update{Update: &tg.Update{
+			ID:      1,
+			Message: &tg.Message{Text: "hello"},
+		}}
+	}
+
+	panicHandler := func(ctx context.Context, mu *MessageUpdate) error {
+		panic("boom")
+	}
+
+	t.Run("WithoutRecover", func(t *testing.T) {
+		router := NewRouter().Message(panicHandler)
+
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = router.Handle(ctx, newUpdate())
+		})
+	})
+
+	t.Run("ErrorHandler", func(t *testing.T) {
+		update := newUpdate()
+
+		var (
+			hooked  *PanicError
+			handled error
+		)
+
+		router := NewRouter().
+			GlobalUse(Recover(WithRecoverHook(func(ctx context.Context, err *PanicError) {
+				hooked = err
+			}))).
+			Error(func(ctx context.Context, update *Update, err error) error {
+				handled = err
+				return nil
+			}).
+			Message(panicHandler)
+
+		require.NoError(t, router.Handle(ctx, update))
+
+		var panicErr *PanicError
+		require.ErrorAs(t, handled, &panicErr)
+		assert.Same(t, hooked, panicErr)
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.Same(t, update, panicErr.Update)
+		assert.Contains(t, string(panicErr.Stack), "recover_test.go")
+		assert.EqualError(t, panicErr, "panic: boom")
+	})
+
+	t.Run("NoErrorHandler", func(t *testing.T) {
+		router := NewRouter().
+			GlobalUse(Recover()).
+			Message(panicHandler)
+
+		var panicErr *PanicError
+		assert.ErrorAs(t, router.Handle(ctx, newUpdate()), &panicErr)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		errPanic := errors.New("middleware")
+
+		router := NewRouter().
+			GlobalUse(
+				Recover(),
+				func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+					panic(errPanic)
+				},
+			).
+			Message(func(ctx context.Context, mu *MessageUpdate) error { return nil })
+
+		assert.ErrorIs(t, router.Handle(ctx, newUpdate()), errPanic)
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		var handled []string
+
+		router := NewRouter().
+			GlobalUse(Recover()).
+			Error(func(ctx context.Context, update *Update, err error) error {
+				handled = append(handled, "router")
+				return nil
+			})
+
+		router.Group().
+			Error(func(ctx context.Context, update *Update, err error) error {
+				handled = append(handled, "group")
+				return nil
+			}).
+			Message(panicHandler)
+
+		require.NoError(t, router.Handle(ctx, newUpdate()))
+		assert.Equal(t, []string{"group"}, handled)
+	})
+}
+
+func TestSafeHandle(t *testing.T) {
+	update := &Update{Update: &tg.Update{ID: 1}}
+
+	err := safeHandle(context.Background(), HandlerFunc(func(ctx context.Context, update *Update) error {
+		panic("boom")
+	}), update)
+
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.Same(t, update, panicErr.Update)
+
+	err = safeHandle(context.Background(), HandlerFunc(func(ctx context.Context, update *Update) error {
+		return nil
+	}), update)
+	assert.NoError(t, err)
+}
+
+func TestPoller_Panic(t *testing.T) {
+	poller := NewPoller(HandlerFunc(func(ctx context.Context, update *Update) error {
+		panic("boom")
+	}), nil)
+
+	var panicErr *PanicError
+	assert.ErrorAs(t, poller.handleUpdate(context.Background(), &tg.Update{ID: 1}), &panicErr)
+}
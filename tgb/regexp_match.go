@@ -0,0 +1,132 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RegexpMatch is the first match of the regexp in text of the update.
+// It's set to context by [Regexp] filter, see [RegexpMatchFromContext].
+type RegexpMatch struct {
+	// Text is the text of the update which was matched.
+	Text string
+
+	// Groups are the matched text and submatches.
+	// Groups[0] is the whole match, Groups[i] is the i-th submatch.
+	// Group which didn't participate in the match is empty.
+	Groups []string
+
+	// Named are the named submatches, e.g. (?P<name>...).
+	// Group which didn't participate in the match is absent.
+	Named map[string]string
+}
+
+// newRegexpMatch returns first match of re in text or nil if text doesn't match.
+func newRegexpMatch(re *regexp.Regexp, text string) *RegexpMatch {
+	indexes := re.FindStringSubmatchIndex(text)
+	if indexes == nil {
+		return nil
+	}
+
+	match := &RegexpMatch{
+		Text:   text,
+		Groups: make([]string, len(indexes)/2),
+	}
+
+	names := re.SubexpNames()
+
+	for i := range match.Groups {
+		start, end := indexes[2*i], indexes[2*i+1]
+		if start < 0 {
+			continue
+		}
+
+		match.Groups[i] = text[start:end]
+
+		if names[i] != "" {
+			if match.Named == nil {
+				match.Named = make(map[string]string)
+			}
+
+			match.Named[names[i]] = match.Groups[i]
+		}
+	}
+
+	return match
+}
+
+// RegexpMatchFromContext returns match of the [Regexp] filter.
+// It returns nil if handler is not filtered by Regexp.
+func RegexpMatchFromContext(ctx context.Context) *RegexpMatch {
+	match, _ := getFilterValue(ctx, regexpMatchContextKey).(*RegexpMatch)
+	return match
+}
+
+// Group returns the named submatch or empty string if group didn't participate in the match.
+func (match *RegexpMatch) Group(name string) string {
+	return match.Named[name]
+}
+
+// Decode binds named submatches to fields of struct pointed by v.
+// Field is bound to group with name from `regexp` tag or with same name as field (case insensitive).
+// Groups which didn't participate in the match are skipped.
+//
+//	re := regexp.MustCompile(`^(?P<a>\d+)\s*(?P<op>[-+*/])\s*(?P<b>\d+)$`)
+//
+//	type Expr struct {
+//		A  int    `regexp:"a"`
+//		Op string `regexp:"op"`
+//		B  int    `regexp:"b"`
+//	}
+//
+// Supported field types are the same as for [CommandArgs.Decode].
+func (match *RegexpMatch) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode regexp match: expected pointer to struct, got %T", v)
+	}
+
+	rv = rv.Elem()
+	typ := rv.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, ok := field.Tag.Lookup("regexp")
+		if name == "-" {
+			continue
+		}
+
+		var (
+			value string
+			found bool
+		)
+
+		if ok {
+			value, found = match.Named[name]
+		} else {
+			for group, groupValue := range match.Named {
+				if strings.EqualFold(group, field.Name) {
+					name, value, found = group, groupValue, true
+					break
+				}
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		if err := setStringValue(rv.Field(i), value); err != nil {
+			return fmt.Errorf("decode regexp match: group %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
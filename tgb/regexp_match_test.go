@@ -0,0 +1,88 @@
+package tgb
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegexpMatch(t *testing.T) {
+	re := regexp.MustCompile(`^(?P<a>\d+)\s*(?P<op>[-+*/])\s*(?P<b>\d+)(\s*=\s*(?P<result>\d+))?$`)
+
+	assert.Nil(t, newRegexpMatch(re, "hello"))
+
+	match := newRegexpMatch(re, "2 + 3")
+	require.NotNil(t, match)
+
+	assert.Equal(t, "2 + 3", match.Text)
+	assert.Equal(t, []string{"2 + 3", "2", "+", "3", "", ""}, match.Groups)
+	assert.Equal(t, map[string]string{"a": "2", "op": "+", "b": "3"}, match.Named)
+	assert.Equal(t, "+", match.Group("op"))
+	assert.Equal(t, "", match.Group("result"))
+
+	match = newRegexpMatch(regexp.MustCompile(`world`), "hello world")
+	require.NotNil(t, match)
+	assert.Equal(t, []string{"world"}, match.Groups)
+	assert.Nil(t, match.Named)
+}
+
+func TestRegexpMatch_Decode(t *testing.T) {
+	re := regexp.MustCompile(`^(?P<a>\d+)\s*(?P<op>[-+*/])\s*(?P<b>\w+)(\s*=\s*(?P<result>\d+))?$`)
+
+	type expr struct {
+		A      int
+		Op     string `regexp:"op"`
+		Second int    `regexp:"b"`
+		Result *int
+		Skip   string `regexp:"-"`
+	}
+
+	t.Run("Ok", func(t *testing.T) {
+		var v expr
+		require.NoError(t, newRegexpMatch(re, "2 * 21").Decode(&v))
+		assert.Equal(t, expr{A: 2, Op: "*", Second: 21}, v)
+
+		require.NoError(t, newRegexpMatch(re, "2 * 21 = 42").Decode(&v))
+		require.NotNil(t, v.Result)
+		assert.Equal(t, 42, *v.Result)
+	})
+
+	t.Run("InvalidValue", func(t *testing.T) {
+		var v expr
+		err := newRegexpMatch(re, "2 * x").Decode(&v)
+		assert.EqualError(t, err, "decode regexp match: group b: invalid integer")
+	})
+
+	t.Run("NotStruct", func(t *testing.T) {
+		var v int
+		assert.Error(t, newRegexpMatch(re, "2 * 21").Decode(&v))
+	})
+}
+
+func TestRegexpMatchFromContext(t *testing.T) {
+	assert.Nil(t, RegexpMatchFromContext(context.Background()))
+
+	var called bool
+
+	router := NewRouter().
+		Message(func(ctx context.Context, msg *MessageUpdate) error {
+			called = true
+
+			match := RegexpMatchFromContext(ctx)
+			require.NotNil(t, match)
+			assert.Equal(t, "42", match.Group("id"))
+
+			return nil
+		}, Regexp(regexp.MustCompile(`^/order_(?P<id>\d+)$`)))
+
+	err := router.Handle(context.Background(), &Update{Update: &tg.Update{Message: &tg.Message{
+		Chat: tg.Chat{ID: 1},
+		Text: "/order_42",
+	}}})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
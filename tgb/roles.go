@@ -0,0 +1,298 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nosefu/go-tg"
+	"golang.org/x/exp/slices"
+)
+
+// Role is a role of the user, e.g. [RoleAdmin].
+// Any custom roles can be used.
+type Role string
+
+// Predefined roles. Roles are not hierarchical,
+// so list all roles which have access, e.g. HasRole(RoleOwner, RoleAdmin).
+const (
+	RoleOwner     Role = "owner"
+	RoleAdmin     Role = "admin"
+	RoleModerator Role = "moderator"
+	RoleUser      Role = "user"
+)
+
+// RoleAssignment is a set of roles of the user.
+// Roles with zero ChatID are global and apply to all chats.
+type RoleAssignment struct {
+	ChatID tg.ChatID
+	UserID tg.UserID
+	Roles  []Role
+}
+
+// RoleStore define interface for storage of user roles.
+// See [RoleStoreMemory] for example.
+type RoleStore interface {
+	// GetRoles returns global roles of the user merged with roles in the chat.
+	GetRoles(ctx context.Context, chatID tg.ChatID, userID tg.UserID) ([]Role, error)
+
+	// SetRoles replaces roles of the user in the chat or global roles, if chatID is zero.
+	// Empty roles removes the assignment.
+	SetRoles(ctx context.Context, chatID tg.ChatID, userID tg.UserID, roles []Role) error
+
+	// List returns all assignments.
+	List(ctx context.Context) ([]RoleAssignment, error)
+}
+
+type roleStoreKey struct {
+	chatID tg.ChatID
+	userID tg.UserID
+}
+
+// RoleStoreMemory is an in-memory store of user roles.
+// It implements [RoleStore] and is thread-safe.
+type RoleStoreMemory struct {
+	lock  sync.RWMutex
+	roles map[roleStoreKey][]Role
+}
+
+var _ RoleStore = (*RoleStoreMemory)(nil)
+
+// NewRoleStoreMemory creates a new RoleStoreMemory with static assignments.
+func NewRoleStoreMemory(assignments ...RoleAssignment) *RoleStoreMemory {
+	store := &RoleStoreMemory{
+		roles: make(map[roleStoreKey][]Role, len(assignments)),
+	}
+
+	for _, assignment := range assignments {
+		key := roleStoreKey{chatID: assignment.ChatID, userID: assignment.UserID}
+		store.roles[key] = mergeRoles(store.roles[key], assignment.Roles)
+	}
+
+	return store
+}
+
+func (store *RoleStoreMemory) GetRoles(ctx context.Context, chatID tg.ChatID, userID tg.UserID) ([]Role, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	roles := store.roles[roleStoreKey{userID: userID}]
+
+	if chatID != 0 {
+		roles = mergeRoles(roles, store.roles[roleStoreKey{chatID: chatID, userID: userID}])
+	}
+
+	return slices.Clone(roles), nil
+}
+
+func (store *RoleStoreMemory) SetRoles(ctx context.Context, chatID tg.ChatID, userID tg.UserID, roles []Role) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	key := roleStoreKey{chatID: chatID, userID: userID}
+
+	if len(roles) == 0 {
+		delete(store.roles, key)
+	} else {
+		store.roles[key] = mergeRoles(nil, roles)
+	}
+
+	return nil
+}
+
+func (store *RoleStoreMemory) List(ctx context.Context) ([]RoleAssignment, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	assignments := make([]RoleAssignment, 0, len(store.roles))
+	for key, roles := range store.roles {
+		assignments = append(assignments, RoleAssignment{
+			ChatID: key.chatID,
+			UserID: key.userID,
+			Roles:  slices.Clone(roles),
+		})
+	}
+
+	sort.Slice(assignments, func(i, j int) bool {
+		if assignments[i].ChatID != assignments[j].ChatID {
+			return assignments[i].ChatID < assignments[j].ChatID
+		}
+
+		return assignments[i].UserID < assignments[j].UserID
+	})
+
+	return assignments, nil
+}
+
+// mergeRoles returns roles of a and b without duplicates.
+func mergeRoles(a, b []Role) []Role {
+	result := slices.Clone(a)
+
+	for _, role := range b {
+		if !slices.Contains(result, role) {
+			result = append(result, role)
+		}
+	}
+
+	return result
+}
+
+// Roles creates middleware that puts roles of the user who caused the update into context.
+// Roles are global roles of the user merged with roles in the chat of the update.
+// Use [RolesFromContext] to get them and [HasRole] to filter handlers.
+func Roles(store RoleStore) GlobalMiddlewareFunc {
+	return func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+		var roles []Role
+
+		if user := getUpdateUser(update); user != nil {
+			var chatID tg.ChatID
+			if chat := update.Chat(); chat != nil {
+				chatID = chat.ID
+			}
+
+			var err error
+
+			roles, err = store.GetRoles(ctx, chatID, user.ID)
+			if err != nil {
+				return ctx, update, fmt.Errorf("get roles of user %d: %w", user.ID, err)
+			}
+		}
+
+		return context.WithValue(ctx, rolesContextKey, roles), update, nil
+	}
+}
+
+// RolesFromContext returns roles put into context by [Roles] middleware.
+func RolesFromContext(ctx context.Context) []Role {
+	roles, _ := ctx.Value(rolesContextKey).([]Role)
+	return roles
+}
+
+type roleFilter struct {
+	roles []Role
+}
+
+// HasRole checks if user who caused the update has any of specified roles.
+// Roles should be put into context by [Roles] middleware, otherwise filter returns error.
+//
+// When HasRole is passed to [Router.Group], [Router.Mount] or handler registration,
+// directly or nested into [All], required roles are taken into account by [Router.Commands].
+// HasRole nested into [Any] or [Not] is ignored by [Router.Commands],
+// so commands guarded this way are shown to everyone.
+func HasRole(roles ...Role) Filter {
+	return &roleFilter{roles: roles}
+}
+
+// Allow implements Filter interface.
+func (filter *roleFilter) Allow(ctx context.Context, update *Update) (bool, error) {
+	roles, ok := ctx.Value(rolesContextKey).([]Role)
+	if !ok {
+		return false, fmt.Errorf("has role filter: roles are not in context, use tgb.Roles middleware")
+	}
+
+	for _, role := range roles {
+		if slices.Contains(filter.roles, role) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getFiltersRoles returns roles required by HasRole filters, including nested into All.
+func getFiltersRoles(filters []Filter) [][]Role {
+	var result [][]Role
+
+	for _, filter := range filters {
+		switch filter := filter.(type) {
+		case *roleFilter:
+			result = append(result, filter.roles)
+		case allFilter:
+			result = append(result, getFiltersRoles(filter)...)
+		}
+	}
+
+	return result
+}
+
+// hasRequiredRoles checks if roles contain any role of each required set.
+func hasRequiredRoles(required [][]Role, roles []Role) bool {
+	for _, set := range required {
+		found := false
+
+		for _, role := range set {
+			if slices.Contains(roles, role) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetRoleCommands sets commands of the router visible for the user according to roles, see [Router.Commands].
+// Commands for global roles are set in private chat with the user,
+// commands for roles in the chat are set for the user in that chat.
+// If user has no roles or no commands are visible, commands for the scope are deleted, so default ones are shown.
+//
+// Call it after roles of the user are changed.
+func SetRoleCommands(ctx context.Context, client *tg.Client, router *Router, store RoleStore, chatID tg.ChatID, userID tg.UserID) error {
+	roles, err := store.GetRoles(ctx, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("get roles of user %d: %w", userID, err)
+	}
+
+	var scope tg.BotCommandScope
+	if chatID == 0 {
+		scope = tg.BotCommandScopeChat{ChatID: tg.ChatID(userID)}
+	} else {
+		scope = tg.BotCommandScopeChatMember{ChatID: chatID, UserID: int(userID)}
+	}
+
+	commands := router.Commands(roles...)
+
+	if len(roles) == 0 || len(commands) == 0 {
+		if err := client.DeleteMyCommands().Scope(scope).DoVoid(ctx); err != nil {
+			return fmt.Errorf("delete commands of user %d: %w", userID, err)
+		}
+
+		return nil
+	}
+
+	if err := client.SetMyCommands(commands).Scope(scope).DoVoid(ctx); err != nil {
+		return fmt.Errorf("set commands of user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+// SyncRoleCommands sets commands available without roles as default commands
+// and calls [SetRoleCommands] for each assignment of the store.
+func SyncRoleCommands(ctx context.Context, client *tg.Client, router *Router, store RoleStore) error {
+	if commands := router.Commands(); len(commands) > 0 {
+		if err := client.SetMyCommands(commands).DoVoid(ctx); err != nil {
+			return fmt.Errorf("set default commands: %w", err)
+		}
+	} else if err := client.DeleteMyCommands().DoVoid(ctx); err != nil {
+		return fmt.Errorf("delete default commands: %w", err)
+	}
+
+	assignments, err := store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list role assignments: %w", err)
+	}
+
+	for _, assignment := range assignments {
+		if err := SetRoleCommands(ctx, client, router, store, assignment.ChatID, assignment.UserID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleStoreMemory(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewRoleStoreMemory(
+		RoleAssignment{UserID: 1, Roles: []Role{RoleOwner}},
+		RoleAssignment{ChatID: -100, UserID: 1, Roles: []Role{RoleAdmin, RoleOwner}},
+		RoleAssignment{ChatID: -100, UserID: 2, Roles: []Role{RoleModerator}},
+	)
+
+	roles, err := store.GetRoles(ctx, -100, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Role{RoleOwner, RoleAdmin}, roles)
+
+	roles, err = store.GetRoles(ctx, -200, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Role{RoleOwner}, roles)
+
+	roles, err = store.GetRoles(ctx, 0, 2)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	require.NoError(t, store.SetRoles(ctx, -100, 2, nil))
+	require.NoError(t, store.SetRoles(ctx, 0, 3, []Role{RoleUser, RoleUser}))
+
+	assignments, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []RoleAssignment{
+		{ChatID: -100, UserID: 1, Roles: []Role{RoleAdmin, RoleOwner}},
+		{UserID: 1, Roles: []Role{RoleOwner}},
+		{UserID: 3, Roles: []Role{RoleUser}},
+	}, assignments)
+}
+
+func newTestRolesRouter(store RoleStore, calls *[]string) *Router {
+	handler := func(name string) MessageHandler {
+		return func(ctx context.Context, msg *MessageUpdate) error {
+			*calls = append(*calls, name)
+			return nil
+		}
+	}
+
+	router := NewRouter().
+		GlobalUse(Roles(store)).
+		Message(handler("start"), Command("start", WithCommandDescription("start bot"))).
+		Message(handler("help"), Command("help"))
+
+	staff := router.Group(HasRole(RoleOwner, RoleAdmin, RoleModerator))
+	staff.Message(handler("mute"), Command("mute", WithCommandDescription("mute user")))
+	staff.Message(handler("ban"), Command("ban", WithCommandDescription("ban user")), HasRole(RoleOwner, RoleAdmin))
+
+	owner := router.Group(HasRole(RoleOwner))
+	owner.Message(handler("config"), Command("config", WithCommandDescription("configure bot")))
+	owner.Message(handler("start"), Command("start", WithCommandDescription("start bot as owner")))
+
+	return router
+}
+
+func TestRoles(t *testing.T) {
+	store := NewRoleStoreMemory(
+		RoleAssignment{UserID: 1, Roles: []Role{RoleOwner}},
+		RoleAssignment{ChatID: -100, UserID: 2, Roles: []Role{RoleModerator}},
+	)
+
+	var calls []string
+
+	router := newTestRolesRouter(store, &calls)
+
+	t.Run("Handle", func(t *testing.T) {
+		testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+			handle := func(chatID tg.ChatID, userID tg.UserID, text string) {
+				err := router.Handle(ctx, &Update{Client: client, Update: &tg.Update{Message: &tg.Message{
+					Chat: tg.Chat{ID: chatID},
+					From: &tg.User{ID: userID},
+					Text: text,
+				}}})
+				require.NoError(t, err)
+			}
+
+			handle(1, 1, "/config")
+			handle(-100, 2, "/mute")
+			handle(-100, 2, "/ban")
+			handle(-100, 2, "/config")
+			handle(-200, 2, "/mute")
+			handle(-100, 1, "/ban")
+
+			assert.Equal(t, []string{"config", "mute", "ban"}, calls)
+		}, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":12345,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		})
+	})
+
+	t.Run("RolesFromContext", func(t *testing.T) {
+		assert.Nil(t, RolesFromContext(context.Background()))
+
+		ctx, _, err := Roles(store)(context.Background(), &Update{Update: &tg.Update{Message: &tg.Message{
+			Chat: tg.Chat{ID: -100},
+			From: &tg.User{ID: 2},
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, []Role{RoleModerator}, RolesFromContext(ctx))
+	})
+
+	t.Run("HasRoleWithoutMiddleware", func(t *testing.T) {
+		_, err := HasRole(RoleOwner).Allow(context.Background(), &Update{Update: &tg.Update{}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Commands", func(t *testing.T) {
+		assert.Equal(t, []tg.BotCommand{
+			{Command: "start", Description: "start bot"},
+		}, router.Commands())
+
+		assert.Equal(t, []tg.BotCommand{
+			{Command: "start", Description: "start bot"},
+			{Command: "mute", Description: "mute user"},
+		}, router.Commands(RoleModerator))
+
+		assert.Equal(t, []tg.BotCommand{
+			{Command: "start", Description: "start bot"},
+			{Command: "mute", Description: "mute user"},
+			{Command: "ban", Description: "ban user"},
+			{Command: "config", Description: "configure bot"},
+		}, router.Commands(RoleOwner))
+	})
+
+	t.Run("CommandsNestedIntoAll", func(t *testing.T) {
+		handler := func(ctx context.Context, msg *MessageUpdate) error { return nil }
+
+		router := NewRouter().
+			Message(handler, Command("start", WithCommandDescription("start bot")))
+
+		router.Group(All(ChatType(tg.ChatTypePrivate), HasRole(RoleAdmin))).
+			Message(handler, Command("config", WithCommandDescription("configure bot")))
+
+		assert.Equal(t, []tg.BotCommand{
+			{Command: "start", Description: "start bot"},
+		}, router.Commands())
+
+		assert.Equal(t, []tg.BotCommand{
+			{Command: "start", Description: "start bot"},
+			{Command: "config", Description: "configure bot"},
+		}, router.Commands(RoleAdmin))
+	})
+}
+
+func TestSyncRoleCommands(t *testing.T) {
+	var requests []string
+
+	testWithClientLocal(t, func(t *testing.T, ctx context.Context, client *tg.Client) {
+		store := NewRoleStoreMemory(
+			RoleAssignment{UserID: 1, Roles: []Role{RoleOwner}},
+			RoleAssignment{ChatID: -100, UserID: 2, Roles: []Role{RoleModerator}},
+		)
+
+		router := newTestRolesRouter(store, new([]string))
+
+		require.NoError(t, SyncRoleCommands(ctx, client, router, store))
+
+		require.NoError(t, store.SetRoles(ctx, -100, 2, nil))
+		require.NoError(t, SetRoleCommands(ctx, client, router, store, -100, 2))
+
+		assert.Equal(t, []string{
+			`setMyCommands  [{"command":"start","description":"start bot"}]`,
+			`setMyCommands {"type":"chat_member","chat_id":-100,"user_id":2} [{"command":"start","description":"start bot"},{"command":"mute","description":"mute user"}]`,
+			`setMyCommands {"type":"chat","chat_id":1} [{"command":"start","description":"start bot"},{"command":"mute","description":"mute user"},{"command":"ban","description":"ban user"},{"command":"config","description":"configure bot"}]`,
+			`deleteMyCommands {"type":"chat_member","chat_id":-100,"user_id":2} `,
+		}, requests)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot12345:secret/setMyCommands":
+			requests = append(requests, "setMyCommands "+r.FormValue("scope")+" "+r.FormValue("commands"))
+		case "/bot12345:secret/deleteMyCommands":
+			requests = append(requests, "deleteMyCommands "+r.FormValue("scope")+" "+r.FormValue("commands"))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	})
+}
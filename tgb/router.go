@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/nosefu/go-tg"
 )
@@ -21,6 +22,13 @@ type Router struct {
 	typedHandlers  map[tg.UpdateType][]Handler
 	updateHandlers []Handler
 
+	observers []Handler
+
+	subRouters []Handler
+	mounted    []mountedRouter
+
+	commands []routerCommand
+
 	defaultHandler Handler
 	errorHandler   ErrorHandler
 }
@@ -31,9 +39,6 @@ func NewRouter() *Router {
 		chain:         chain{},
 		globalChain:   globalChain{},
 		typedHandlers: map[tg.UpdateType][]Handler{},
-		defaultHandler: HandlerFunc(func(ctx context.Context, update *Update) error {
-			return nil
-		}),
 	}
 }
 
@@ -49,6 +54,14 @@ func compactFilters(filters ...Filter) Filter {
 var (
 	// ErrFilterNoAllow is returned when filter doesn't allow to handle Update.
 	ErrFilterNoAllow = fmt.Errorf("filter no allow")
+
+	// ErrNext can be returned by handler to pass the Update to the next matching handler.
+	// If there is no such handler, the Update is passed to the default handler.
+	ErrNext = fmt.Errorf("next handler")
+
+	// ErrDropUpdate can be returned by GlobalMiddlewareFunc to stop Update processing silently.
+	// Handlers and error handler are not called in that case.
+	ErrDropUpdate = fmt.Errorf("drop update")
 )
 
 func filterMiddleware(filter Filter) Middleware {
@@ -58,6 +71,9 @@ func filterMiddleware(filter Filter) Middleware {
 				return next.Handle(ctx, update)
 			}
 
+			// filters can pass values to handler, e.g. parsed command arguments
+			ctx = withFilterValues(ctx)
+
 			allow, err := filter.Allow(ctx, update)
 			if err != nil {
 				return fmt.Errorf("filter error: %w", err)
@@ -94,6 +110,8 @@ func (bot *Router) register(typ tg.UpdateType, handler Handler, filters ...Filte
 		bot.chain.Append(filterMiddleware(filter)).Then(handler),
 	)
 
+	bot.registerCommands(filters)
+
 	return bot
 }
 
@@ -226,20 +244,239 @@ func (bot *Router) Update(handler HandlerFunc, filters ...Filter) *Router {
 		bot.chain.Append(filterMiddleware(fitler)).Then(handler),
 	)
 
+	bot.registerCommands(filters)
+
+	return bot
+}
+
+// Observe registers a handler which is called for each Update matched by filters,
+// before other handlers and without consuming the Update.
+// It is useful for analytics and logging.
+//
+// Observers are called in order of registration.
+// Errors returned by observer are passed to the error handler,
+// routing continues if it returns nil.
+// Observers don't affect [Router.AllowedUpdates].
+func (bot *Router) Observe(handler HandlerFunc, filters ...Filter) *Router {
+	filter := compactFilters(filters...)
+
+	bot.observers = append(bot.observers,
+		filterMiddleware(filter).Wrap(handler),
+	)
+
+	return bot
+}
+
+// Default registers a handler which is called if no other handler matches the update.
+// By default, such updates are ignored.
+func (bot *Router) Default(handler HandlerFunc) *Router {
+	bot.defaultHandler = handler
+	return bot
+}
+
+// Group creates a sub-router and mounts it with specified filters.
+// See [Router.Mount] for details.
+func (bot *Router) Group(filters ...Filter) *Router {
+	sub := NewRouter()
+	bot.Mount(sub, filters...)
+	return sub
+}
+
+// Mount registers a sub-router, which handles the update only if filters match it.
+// Sub-router has own global middlewares, error handler and default handler.
+// Global middlewares of the router are applied before sub-router ones.
+//
+// Sub-routers are checked in order of mounting, after handlers of the router.
+// If no handler of sub-router matches the update and sub-router has no default handler,
+// the update is passed to the next sub-router or to the default handler of the router.
+// Errors returned by sub-router (including its error handler) are passed to the error handler of the router.
+func (bot *Router) Mount(sub *Router, filters ...Filter) *Router {
+	filter := compactFilters(filters...)
+
+	bot.subRouters = append(bot.subRouters,
+		filterMiddleware(filter).Wrap(HandlerFunc(func(ctx context.Context, update *Update) error {
+			return sub.handle(ctx, update, true)
+		})),
+	)
+	bot.mounted = append(bot.mounted, mountedRouter{
+		router: sub,
+		roles:  getFiltersRoles(filters),
+	})
+
 	return bot
 }
 
+// mountedRouter is a sub-router with roles required by filters it's mounted with.
+type mountedRouter struct {
+	router *Router
+	roles  [][]Role
+}
+
+// routerCommand is a command with description registered in router, see [Router.Commands].
+type routerCommand struct {
+	command tg.BotCommand
+	roles   [][]Role
+}
+
+// registerCommands remembers commands with description from filters of the handler.
+func (bot *Router) registerCommands(filters []Filter) {
+	for _, filter := range filters {
+		filter, ok := filter.(*commandFilter)
+		if !ok || filter.description == "" || !strings.Contains(filter.prefixies, "/") {
+			continue
+		}
+
+		bot.commands = append(bot.commands, routerCommand{
+			command: tg.BotCommand{
+				Command:     filter.commands[0],
+				Description: filter.description,
+			},
+			roles: getFiltersRoles(filters),
+		})
+	}
+}
+
+// Commands returns commands registered in router and mounted sub-routers,
+// which are visible for user with specified roles.
+// Only commands with description are returned, see [WithCommandDescription].
+// Command is visible if user has any of roles of each [HasRole] filter,
+// passed to handler registration or mounting of sub-routers.
+//
+// Result can be passed to setMyCommands, see [SetRoleCommands].
+func (bot *Router) Commands(roles ...Role) []tg.BotCommand {
+	var result []tg.BotCommand
+
+	add := func(command tg.BotCommand) {
+		for _, v := range result {
+			if v.Command == command.Command {
+				return
+			}
+		}
+
+		result = append(result, command)
+	}
+
+	for _, command := range bot.commands {
+		if hasRequiredRoles(command.roles, roles) {
+			add(command.command)
+		}
+	}
+
+	for _, sub := range bot.mounted {
+		if !hasRequiredRoles(sub.roles, roles) {
+			continue
+		}
+
+		for _, command := range sub.router.Commands(roles...) {
+			add(command)
+		}
+	}
+
+	return result
+}
+
+// allowedUpdatesProvider is implemented by handlers which know update types they handle.
+// It's used by Poller and Webhook to request only that update types,
+// if allowed updates are not specified explicitly.
+type allowedUpdatesProvider interface {
+	AllowedUpdates() []tg.UpdateType
+}
+
+var _ allowedUpdatesProvider = (*Router)(nil)
+
+// allUpdateTypes returns all known update types.
+func allUpdateTypes() []tg.UpdateType {
+	result := make([]tg.UpdateType, 0, int(tg.UpdateTypeDeletedBusinessMessages))
+
+	for typ := tg.UpdateTypeMessage; typ <= tg.UpdateTypeDeletedBusinessMessages; typ++ {
+		result = append(result, typ)
+	}
+
+	return result
+}
+
+// AllowedUpdates returns update types which have registered handlers, including mounted sub-routers.
+// If generic Update handler is registered, all update types are returned,
+// including chat_member, message_reaction and message_reaction_count which Telegram doesn't send by default.
+//
+// Poller and Webhook use it by default, when Router is passed as handler.
+func (bot *Router) AllowedUpdates() []tg.UpdateType {
+	if len(bot.updateHandlers) > 0 {
+		return allUpdateTypes()
+	}
+
+	types := make(map[tg.UpdateType]bool, len(bot.typedHandlers))
+
+	for typ, handlers := range bot.typedHandlers {
+		if len(handlers) > 0 {
+			types[typ] = true
+		}
+	}
+
+	for _, sub := range bot.mounted {
+		for _, typ := range sub.router.AllowedUpdates() {
+			types[typ] = true
+		}
+	}
+
+	result := make([]tg.UpdateType, 0, len(types))
+
+	for _, typ := range allUpdateTypes() {
+		if types[typ] {
+			result = append(result, typ)
+		}
+	}
+
+	return result
+}
+
 func (bot *Router) getDefaultHandler() Handler {
+	if bot.defaultHandler == nil {
+		return bot.chain.Then(HandlerFunc(func(ctx context.Context, update *Update) error {
+			return nil
+		}))
+	}
+
 	return bot.chain.Then(bot.defaultHandler)
 }
 
 // Handle handles an Update.
 func (bot *Router) Handle(ctx context.Context, update *Update) error {
+	return bot.handle(ctx, update, false)
+}
+
+// handle handles an Update.
+// If router is mounted and no handler handles the update, ErrFilterNoAllow is returned,
+// so parent router can try next handlers.
+func (bot *Router) handle(ctx context.Context, update *Update, mounted bool) (err error) {
+	// deferred first, so callbacks get the final result, including recovered panic
+	if !mounted {
+		var done *updateDone
+		if ctx, done = withUpdateDone(ctx); done != nil {
+			defer func() {
+				err = done.run(ctx, err)
+			}()
+		}
+	}
+
+	// ctx is captured by reference, so Recover applied by any global middleware is taken into account.
+	defer func() {
+		rec := recovererFromContext(ctx)
+		if rec == nil {
+			return
+		}
+
+		if v := recover(); v != nil {
+			err = bot.handlePanic(ctx, update, rec, v)
+		}
+	}()
+
 	// apply middlewares
-	var err error
 	for _, mw := range bot.globalChain {
 		ctx, update, err = mw(ctx, update)
-		if err != nil {
+		if errors.Is(err, ErrDropUpdate) {
+			return nil
+		} else if err != nil {
 			if bot.errorHandler != nil {
 				return bot.errorHandler(ctx, update, err)
 			}
@@ -247,6 +484,21 @@ func (bot *Router) Handle(ctx context.Context, update *Update) error {
 		}
 	}
 
+	for _, observer := range bot.observers {
+		err := observer.Handle(ctx, update)
+		if err == nil || errors.Is(err, ErrFilterNoAllow) || errors.Is(err, ErrNext) {
+			continue
+		}
+
+		if bot.errorHandler == nil {
+			return err
+		}
+
+		if err := bot.errorHandler(ctx, update, err); err != nil {
+			return err
+		}
+	}
+
 	group := append([]Handler{}, bot.updateHandlers...)
 
 	typed, ok := bot.typedHandlers[update.Type()]
@@ -254,12 +506,16 @@ func (bot *Router) Handle(ctx context.Context, update *Update) error {
 		group = append(group, typed...)
 	}
 
+	group = append(group, bot.subRouters...)
+
 	// If no handlers found, use default handler.
-	group = append(group, bot.getDefaultHandler())
+	if bot.defaultHandler != nil || !mounted {
+		group = append(group, bot.getDefaultHandler())
+	}
 
 	for _, handler := range group {
 		err := handler.Handle(ctx, update)
-		if errors.Is(err, ErrFilterNoAllow) {
+		if errors.Is(err, ErrFilterNoAllow) || errors.Is(err, ErrNext) {
 			continue
 		} else if err != nil && bot.errorHandler != nil {
 			return bot.errorHandler(ctx, update, err)
@@ -268,5 +524,9 @@ func (bot *Router) Handle(ctx context.Context, update *Update) error {
 		return err
 	}
 
+	if mounted {
+		return ErrFilterNoAllow
+	}
+
 	return nil
 }
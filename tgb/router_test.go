@@ -2,11 +2,13 @@ package tgb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/nosefu/go-tg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRouter(t *testing.T) {
@@ -566,3 +568,310 @@ func TestRouter(t *testing.T) {
 	})
 
 }
+
+func TestRouter_AllowedUpdates(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		assert.Empty(t, NewRouter().AllowedUpdates())
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		router := NewRouter().
+			MessageReaction(func(ctx context.Context, mru *MessageReactionUpdate) error { return nil }).
+			ChatMember(func(ctx context.Context, cmu *ChatMemberUpdatedUpdate) error { return nil }).
+			Message(func(ctx context.Context, mu *MessageUpdate) error { return nil }).
+			Message(func(ctx context.Context, mu *MessageUpdate) error { return nil }, Command("start")).
+			BusinessMessage(func(ctx context.Context, mu *MessageUpdate) error { return nil })
+
+		assert.Equal(t, []tg.UpdateType{
+			tg.UpdateTypeMessage,
+			tg.UpdateTypeChatMember,
+			tg.UpdateTypeMessageReaction,
+			tg.UpdateTypeBusinessMessage,
+		}, router.AllowedUpdates())
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		router := NewRouter().
+			Message(func(ctx context.Context, mu *MessageUpdate) error { return nil }).
+			Update(func(ctx context.Context, update *Update) error { return nil })
+
+		allowed := router.AllowedUpdates()
+		assert.Len(t, allowed, int(tg.UpdateTypeDeletedBusinessMessages))
+		assert.Contains(t, allowed, tg.UpdateTypeChatMember)
+		assert.Contains(t, allowed, tg.UpdateTypeMessageReactionCount)
+		assert.NotContains(t, allowed, tg.UpdateTypeUnknown)
+	})
+}
+
+func TestRouter_Group(t *testing.T) {
+	ctx := context.Background()
+
+	newUpdate := func(chatID tg.ChatID) *Update {
+		return &Update{Update: &tg.Update{
+			Message: &tg.Message{Chat: tg.Chat{ID: chatID}, Text: "hello"},
+		}}
+	}
+
+	isChat := func(chatID tg.ChatID) Filter {
+		return FilterFunc(func(ctx context.Context, update *Update) (bool, error) {
+			return update.Message.Chat.ID == chatID, nil
+		})
+	}
+
+	t.Run("Routing", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter()
+
+		router.Group(isChat(1)).
+			GlobalUse(func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+				calls = append(calls, "admin middleware")
+				return ctx, update, nil
+			}).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "admin")
+				return nil
+			})
+
+		router.Group(isChat(2)).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "private")
+				return nil
+			}, TextEqual("/start"))
+
+		router.Default(func(ctx context.Context, update *Update) error {
+			calls = append(calls, "default")
+			return nil
+		})
+
+		require.NoError(t, router.Handle(ctx, newUpdate(1)))
+		assert.Equal(t, []string{"admin middleware", "admin"}, calls)
+
+		calls = nil
+		require.NoError(t, router.Handle(ctx, newUpdate(2)))
+		assert.Equal(t, []string{"default"}, calls, "sub-router without match passes update to parent")
+
+		calls = nil
+		require.NoError(t, router.Handle(ctx, newUpdate(3)))
+		assert.Equal(t, []string{"default"}, calls)
+	})
+
+	t.Run("RouterHandlersFirst", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter()
+
+		router.Group().Message(func(ctx context.Context, mu *MessageUpdate) error {
+			calls = append(calls, "group")
+			return nil
+		})
+
+		router.Message(func(ctx context.Context, mu *MessageUpdate) error {
+			calls = append(calls, "router")
+			return nil
+		})
+
+		require.NoError(t, router.Handle(ctx, newUpdate(1)))
+		assert.Equal(t, []string{"router"}, calls)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter()
+
+		router.Group(isChat(1)).Default(func(ctx context.Context, update *Update) error {
+			calls = append(calls, "group default")
+			return nil
+		})
+
+		router.Group().Default(func(ctx context.Context, update *Update) error {
+			calls = append(calls, "fallback")
+			return nil
+		})
+
+		require.NoError(t, router.Handle(ctx, newUpdate(1)))
+		require.NoError(t, router.Handle(ctx, newUpdate(2)))
+		assert.Equal(t, []string{"group default", "fallback"}, calls)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		errHandler := errors.New("handler")
+		errMiddleware := errors.New("middleware")
+
+		var handled []error
+
+		router := NewRouter().Error(func(ctx context.Context, update *Update, err error) error {
+			handled = append(handled, err)
+			return nil
+		})
+
+		router.Group(isChat(1)).
+			Error(func(ctx context.Context, update *Update, err error) error {
+				return fmt.Errorf("group: %w", err)
+			}).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				return errHandler
+			})
+
+		sub := NewRouter().GlobalUse(func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+			return ctx, update, errMiddleware
+		})
+		router.Mount(sub, isChat(2))
+
+		require.NoError(t, router.Handle(ctx, newUpdate(1)))
+		require.NoError(t, router.Handle(ctx, newUpdate(2)))
+
+		require.Len(t, handled, 2)
+		assert.ErrorIs(t, handled[0], errHandler)
+		assert.EqualError(t, handled[0], "group: handler")
+		assert.ErrorIs(t, handled[1], errMiddleware)
+	})
+
+	t.Run("AllowedUpdates", func(t *testing.T) {
+		router := NewRouter().
+			Message(func(ctx context.Context, mu *MessageUpdate) error { return nil })
+
+		router.Group().
+			CallbackQuery(func(ctx context.Context, cqu *CallbackQueryUpdate) error { return nil }).
+			Group().
+			ChatMember(func(ctx context.Context, cmu *ChatMemberUpdatedUpdate) error { return nil })
+
+		assert.Equal(t, []tg.UpdateType{
+			tg.UpdateTypeMessage,
+			tg.UpdateTypeCallbackQuery,
+			tg.UpdateTypeChatMember,
+		}, router.AllowedUpdates())
+	})
+}
+
+func TestRouter_Next(t *testing.T) {
+	ctx := context.Background()
+
+	newUpdate := func() *Update {
+		return &Update{Update: &tg.Update{
+			Message: &tg.Message{Text: "hello"},
+		}}
+	}
+
+	t.Run("Fallthrough", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter().
+			Update(func(ctx context.Context, update *Update) error {
+				calls = append(calls, "update")
+				return ErrNext
+			}).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "first")
+				return fmt.Errorf("wrapped: %w", ErrNext)
+			}).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "second")
+				return nil
+			}).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "third")
+				return nil
+			})
+
+		require.NoError(t, router.Handle(ctx, newUpdate()))
+		assert.Equal(t, []string{"update", "first", "second"}, calls)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter().
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "message")
+				return ErrNext
+			}).
+			Default(func(ctx context.Context, update *Update) error {
+				calls = append(calls, "default")
+				return ErrNext
+			})
+
+		require.NoError(t, router.Handle(ctx, newUpdate()))
+		assert.Equal(t, []string{"message", "default"}, calls)
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter()
+
+		router.Group().Message(func(ctx context.Context, mu *MessageUpdate) error {
+			calls = append(calls, "group")
+			return ErrNext
+		})
+
+		router.Default(func(ctx context.Context, update *Update) error {
+			calls = append(calls, "default")
+			return nil
+		})
+
+		require.NoError(t, router.Handle(ctx, newUpdate()))
+		assert.Equal(t, []string{"group", "default"}, calls)
+	})
+}
+
+func TestRouter_Observe(t *testing.T) {
+	ctx := context.Background()
+
+	update := &Update{Update: &tg.Update{
+		Message: &tg.Message{Text: "hello"},
+	}}
+
+	t.Run("NotConsume", func(t *testing.T) {
+		var calls []string
+
+		router := NewRouter().
+			Observe(func(ctx context.Context, update *Update) error {
+				calls = append(calls, "observer")
+				return nil
+			}).
+			Observe(func(ctx context.Context, update *Update) error {
+				calls = append(calls, "skipped observer")
+				return nil
+			}, TextEqual("bye")).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				calls = append(calls, "message")
+				return nil
+			})
+
+		require.NoError(t, router.Handle(ctx, update))
+		assert.Equal(t, []string{"observer", "message"}, calls)
+		assert.Equal(t, []tg.UpdateType{tg.UpdateTypeMessage}, router.AllowedUpdates())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		errObserver := errors.New("observer")
+
+		var (
+			handled []error
+			called  bool
+		)
+
+		router := NewRouter().
+			Observe(func(ctx context.Context, update *Update) error {
+				return errObserver
+			}).
+			Message(func(ctx context.Context, mu *MessageUpdate) error {
+				called = true
+				return nil
+			})
+
+		assert.ErrorIs(t, router.Handle(ctx, update), errObserver)
+		assert.False(t, called)
+
+		router.Error(func(ctx context.Context, update *Update, err error) error {
+			handled = append(handled, err)
+			return nil
+		})
+
+		require.NoError(t, router.Handle(ctx, update))
+		assert.True(t, called)
+		assert.Equal(t, []error{errObserver}, handled)
+	})
+}
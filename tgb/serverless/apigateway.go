@@ -0,0 +1,174 @@
+// Package serverless provides adapters to run [tgb.Webhook] in serverless environments.
+//
+// Adapters translate platform events into [tgb.WebhookRequest]
+// and [tgb.WebhookResponse] back into platform responses,
+// so bot can be deployed as function without HTTP server.
+//
+// Event types are defined in this package, so it has no dependencies on platform SDKs,
+// but they are compatible by JSON with the original ones.
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/nosefu/go-tg/tgb"
+)
+
+// securityTokenHeader is the header with webhook secret token.
+const securityTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// APIGatewayProxyRequest is the AWS API Gateway proxy integration event.
+// Both REST API (payload v1.0) and HTTP API (payload v2.0) formats are supported,
+// as well as Lambda function URLs.
+type APIGatewayProxyRequest struct {
+	// HTTPMethod is the request method (payload v1.0).
+	HTTPMethod string `json:"httpMethod,omitempty"`
+
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+
+	Body            string `json:"body,omitempty"`
+	IsBase64Encoded bool   `json:"isBase64Encoded,omitempty"`
+
+	RequestContext APIGatewayProxyRequestContext `json:"requestContext"`
+}
+
+// APIGatewayProxyRequestContext contains request metadata of [APIGatewayProxyRequest].
+type APIGatewayProxyRequestContext struct {
+	// Identity is set in payload v1.0.
+	Identity struct {
+		SourceIP string `json:"sourceIp,omitempty"`
+	} `json:"identity"`
+
+	// HTTP is set in payload v2.0.
+	HTTP struct {
+		Method   string `json:"method,omitempty"`
+		SourceIP string `json:"sourceIp,omitempty"`
+	} `json:"http"`
+}
+
+// APIGatewayProxyResponse is the response of AWS API Gateway proxy integration.
+type APIGatewayProxyResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded,omitempty"`
+}
+
+func (event *APIGatewayProxyRequest) header() http.Header {
+	header := make(http.Header, len(event.Headers)+len(event.MultiValueHeaders))
+
+	for k, vs := range event.MultiValueHeaders {
+		for _, v := range vs {
+			header.Add(k, v)
+		}
+	}
+
+	for k, v := range event.Headers {
+		if header.Get(k) == "" {
+			header.Set(k, v)
+		}
+	}
+
+	return header
+}
+
+func (event *APIGatewayProxyRequest) method() string {
+	if event.HTTPMethod != "" {
+		return event.HTTPMethod
+	}
+
+	return event.RequestContext.HTTP.Method
+}
+
+func (event *APIGatewayProxyRequest) sourceIP() string {
+	if event.RequestContext.HTTP.SourceIP != "" {
+		return event.RequestContext.HTTP.SourceIP
+	}
+
+	return event.RequestContext.Identity.SourceIP
+}
+
+// WebhookRequest translates event to [tgb.WebhookRequest].
+func (event *APIGatewayProxyRequest) WebhookRequest() (*tgb.WebhookRequest, error) {
+	ip, err := netip.ParseAddr(event.sourceIP())
+	if err != nil {
+		return nil, fmt.Errorf("parse source ip: %w", err)
+	}
+
+	body, err := decodeBody(event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	header := event.header()
+
+	return &tgb.WebhookRequest{
+		Method:        strings.ToUpper(event.method()),
+		ContentType:   header.Get("Content-Type"),
+		IP:            ip,
+		SecurityToken: header.Get(securityTokenHeader),
+		Body:          bytes.NewReader(body),
+	}, nil
+}
+
+// NewAPIGatewayProxyResponse translates [tgb.WebhookResponse] to API Gateway response.
+func NewAPIGatewayProxyResponse(response *tgb.WebhookResponse) *APIGatewayProxyResponse {
+	result := &APIGatewayProxyResponse{
+		StatusCode: response.Status,
+		Body:       string(response.Body),
+	}
+
+	if result.StatusCode == 0 {
+		result.StatusCode = http.StatusOK
+	}
+
+	if response.ContentType != "" {
+		result.Headers = map[string]string{
+			"Content-Type": response.ContentType,
+		}
+	}
+
+	return result
+}
+
+// APIGateway returns AWS Lambda handler which serves API Gateway proxy events with webhook.
+// Returned function can be passed directly to lambda.Start.
+//
+// Keep in mind, that function execution may be frozen right after response is returned,
+// so code after [tgb.Update.Reply] in handler is not guaranteed to run.
+//
+// Webhook should be set up before, e.g. on deploy, see [tgb.Webhook.Setup].
+func APIGateway(webhook *tgb.Webhook) func(ctx context.Context, event *APIGatewayProxyRequest) (*APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, event *APIGatewayProxyRequest) (*APIGatewayProxyResponse, error) {
+		request, err := event.WebhookRequest()
+		if err != nil {
+			return &APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    map[string]string{"Content-Type": "text/plain"},
+				Body:       "invalid request",
+			}, nil
+		}
+
+		return NewAPIGatewayProxyResponse(webhook.ServeRequest(ctx, request)), nil
+	}
+}
+
+func decodeBody(body string, isBase64 bool) ([]byte, error) {
+	if !isBase64 {
+		return []byte(body), nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64 body: %w", err)
+	}
+
+	return data, nil
+}
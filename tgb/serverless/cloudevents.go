@@ -0,0 +1,118 @@
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/nosefu/go-tg/tgb"
+)
+
+// CloudEventReplyType is the type of CloudEvent with webhook reply.
+const CloudEventReplyType = "org.telegram.bot.webhook.reply"
+
+// CloudEvent is the CloudEvents v1.0 event in structured JSON mode.
+// Update should be passed in data (or data_base64) attribute.
+//
+// HTTP request metadata is passed via extension attributes:
+//   - sourceip - IP address of the Telegram server, used for subnet check.
+//   - secrettoken - value of the X-Telegram-Bot-Api-Secret-Token header.
+type CloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	DataContentType string `json:"datacontenttype,omitempty"`
+
+	Data       json.RawMessage `json:"data,omitempty"`
+	DataBase64 string          `json:"data_base64,omitempty"`
+
+	SourceIP    string `json:"sourceip,omitempty"`
+	SecretToken string `json:"secrettoken,omitempty"`
+}
+
+// WebhookRequest translates event to [tgb.WebhookRequest].
+// Request method is always POST, content type defaults to application/json.
+func (event *CloudEvent) WebhookRequest() (*tgb.WebhookRequest, error) {
+	ip, err := netip.ParseAddr(event.SourceIP)
+	if err != nil {
+		return nil, fmt.Errorf("parse source ip: %w", err)
+	}
+
+	body := []byte(event.Data)
+	if event.DataBase64 != "" {
+		body, err = decodeBody(event.DataBase64, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	contentType := event.DataContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	return &tgb.WebhookRequest{
+		Method:        http.MethodPost,
+		ContentType:   contentType,
+		IP:            ip,
+		SecurityToken: event.SecretToken,
+		Body:          bytes.NewReader(body),
+	}, nil
+}
+
+// CloudEventError is returned by [CloudEvents] handler when webhook responded with error status.
+type CloudEventError struct {
+	Status int
+	Body   string
+}
+
+func (err *CloudEventError) Error() string {
+	return fmt.Sprintf("webhook response %d: %s", err.Status, err.Body)
+}
+
+// NewCloudEventReply translates [tgb.WebhookResponse] to CloudEvent for source event.
+// Returns nil event if response has no body,
+// and [CloudEventError] if response has error status.
+func NewCloudEventReply(source *CloudEvent, response *tgb.WebhookResponse) (*CloudEvent, error) {
+	if response.Status >= http.StatusBadRequest {
+		return nil, &CloudEventError{
+			Status: response.Status,
+			Body:   string(response.Body),
+		}
+	}
+
+	if len(response.Body) == 0 {
+		return nil, nil
+	}
+
+	return &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              source.ID,
+		Source:          source.Source,
+		Type:            CloudEventReplyType,
+		DataContentType: response.ContentType,
+		Data:            json.RawMessage(response.Body),
+	}, nil
+}
+
+// CloudEvents returns handler which serves CloudEvents with webhook.
+// Webhook reply is returned as event with [CloudEventReplyType] type.
+//
+// Webhook should be set up before, e.g. on deploy, see [tgb.Webhook.Setup].
+func CloudEvents(webhook *tgb.Webhook) func(ctx context.Context, event *CloudEvent) (*CloudEvent, error) {
+	return func(ctx context.Context, event *CloudEvent) (*CloudEvent, error) {
+		request, err := event.WebhookRequest()
+		if err != nil {
+			return nil, &CloudEventError{
+				Status: http.StatusBadRequest,
+				Body:   err.Error(),
+			}
+		}
+
+		return NewCloudEventReply(event, webhook.ServeRequest(ctx, request))
+	}
+}
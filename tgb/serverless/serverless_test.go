@@ -0,0 +1,162 @@
+package serverless
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/nosefu/go-tg/tgb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFixture(t *testing.T, name string, v any) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	require.NoError(t, json.Unmarshal(data, v))
+}
+
+func newTestWebhook(t *testing.T, updates *[]int) *tgb.Webhook {
+	t.Helper()
+
+	return tgb.NewWebhook(
+		tgb.HandlerFunc(func(ctx context.Context, update *tgb.Update) error {
+			*updates = append(*updates, update.ID)
+
+			if update.Message != nil && update.Message.Text == "/start" {
+				return update.Reply(ctx, update.Client.SendMessage(update.Message.Chat, "hello"))
+			}
+
+			return nil
+		}),
+		tg.New("1234:secret"),
+		"https://example.com/webhook",
+		tgb.WithWebhookSecurityToken("secret"),
+	)
+}
+
+func TestAPIGateway(t *testing.T) {
+	t.Run("V1WithReply", func(t *testing.T) {
+		var updates []int
+
+		event := &APIGatewayProxyRequest{}
+		loadFixture(t, "apigateway_v1.json", event)
+
+		response, err := APIGateway(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{1}, updates)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "application/json", response.Headers["Content-Type"])
+		assert.JSONEq(t, `{"method":"sendMessage","chat_id":"1","text":"hello"}`, response.Body)
+	})
+
+	t.Run("V2Base64", func(t *testing.T) {
+		var updates []int
+
+		event := &APIGatewayProxyRequest{}
+		loadFixture(t, "apigateway_v2.json", event)
+
+		response, err := APIGateway(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{2}, updates)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Empty(t, response.Body)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		var updates []int
+
+		event := &APIGatewayProxyRequest{}
+		loadFixture(t, "apigateway_v2.json", event)
+		event.Headers["x-telegram-bot-api-secret-token"] = "invalid"
+
+		response, err := APIGateway(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Empty(t, updates)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		assert.Equal(t, "text/plain", response.Headers["Content-Type"])
+	})
+
+	t.Run("InvalidSourceIP", func(t *testing.T) {
+		var updates []int
+
+		event := &APIGatewayProxyRequest{}
+		loadFixture(t, "apigateway_v1.json", event)
+		event.RequestContext.Identity.SourceIP = ""
+
+		response, err := APIGateway(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
+
+func TestCloudEvents(t *testing.T) {
+	t.Run("Data", func(t *testing.T) {
+		var updates []int
+
+		event := &CloudEvent{}
+		loadFixture(t, "cloudevent.json", event)
+
+		reply, err := CloudEvents(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{3}, updates)
+		assert.Nil(t, reply)
+	})
+
+	t.Run("DataBase64", func(t *testing.T) {
+		var updates []int
+
+		event := &CloudEvent{}
+		loadFixture(t, "cloudevent_base64.json", event)
+
+		reply, err := CloudEvents(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{4}, updates)
+		assert.Nil(t, reply)
+	})
+
+	t.Run("Reply", func(t *testing.T) {
+		var updates []int
+
+		event := &CloudEvent{}
+		loadFixture(t, "cloudevent.json", event)
+		event.Data = json.RawMessage(`{"update_id":5,"message":{"message_id":5,"date":1700000000,"chat":{"id":1,"type":"private"},"text":"/start"}}`)
+
+		reply, err := CloudEvents(newTestWebhook(t, &updates))(context.Background(), event)
+		require.NoError(t, err)
+
+		require.NotNil(t, reply)
+		assert.Equal(t, CloudEventReplyType, reply.Type)
+		assert.Equal(t, event.ID, reply.ID)
+		assert.Equal(t, "application/json", reply.DataContentType)
+		assert.JSONEq(t, `{"method":"sendMessage","chat_id":"1","text":"hello"}`, string(reply.Data))
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		var updates []int
+
+		event := &CloudEvent{}
+		loadFixture(t, "cloudevent.json", event)
+		event.SourceIP = "1.1.1.1"
+
+		_, err := CloudEvents(newTestWebhook(t, &updates))(context.Background(), event)
+
+		var ceErr *CloudEventError
+		require.ErrorAs(t, err, &ceErr)
+		assert.Equal(t, http.StatusForbidden, ceErr.Status)
+		assert.Empty(t, updates)
+	})
+}
@@ -1,6 +1,10 @@
 package session
 
-import "context"
+import (
+	"context"
+
+	"github.com/nosefu/go-tg/tgb"
+)
 
 // Store define interface for session persistance.
 // All stores should have read, write and delete methods.
@@ -16,3 +20,6 @@ type Store interface {
 	// Del deletes a session data.
 	Del(ctx context.Context, key string) error
 }
+
+// Store can be used as storage of oversized callback data.
+var _ tgb.CallbackDataStore = (Store)(nil)
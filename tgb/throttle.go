@@ -0,0 +1,267 @@
+package tgb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleLimit defines token bucket parameters.
+// Key can trigger Burst updates at once, then one update per Every.
+type ThrottleLimit struct {
+	Burst int
+	Every time.Duration
+}
+
+// ThrottleResult is the result of [ThrottleStore.Take].
+type ThrottleResult struct {
+	// Allowed is true if token was taken.
+	Allowed bool
+
+	// RetryAfter is the duration after which next token will be available, if not allowed.
+	RetryAfter time.Duration
+
+	// Exceeded is the number of consecutive not allowed updates including current one.
+	Exceeded int
+}
+
+// ThrottleStore define interface for storage of token buckets.
+// Shared store (e.g. Redis with Lua script) allows to apply limits across replicas.
+// See [ThrottleStoreMemory] for example.
+type ThrottleStore interface {
+	// Take takes one token from the bucket of key.
+	// Check and take should be atomic.
+	Take(ctx context.Context, key string, limit ThrottleLimit) (ThrottleResult, error)
+}
+
+// ThrottleStoreMemory is an in-memory store of token buckets.
+// Full buckets are forgotten periodically, so memory usage depends on number of active keys only.
+// It implements [ThrottleStore] and is thread-safe.
+type ThrottleStoreMemory struct {
+	now func() time.Time
+
+	lock      sync.Mutex
+	buckets   map[string]*throttleBucket
+	lastSweep time.Time
+}
+
+type throttleBucket struct {
+	tokens   float64
+	updated  time.Time
+	exceeded int
+	limit    ThrottleLimit
+}
+
+var _ ThrottleStore = (*ThrottleStoreMemory)(nil)
+
+// throttleSweepInterval is interval between sweeps of full buckets in ThrottleStoreMemory.
+const throttleSweepInterval = time.Minute
+
+// NewThrottleStoreMemory creates a new ThrottleStoreMemory.
+func NewThrottleStoreMemory() *ThrottleStoreMemory {
+	return &ThrottleStoreMemory{
+		now:     time.Now,
+		buckets: make(map[string]*throttleBucket),
+	}
+}
+
+func (store *ThrottleStoreMemory) Take(ctx context.Context, key string, limit ThrottleLimit) (ThrottleResult, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := store.now()
+
+	if now.Sub(store.lastSweep) >= throttleSweepInterval {
+		store.sweep(now)
+		store.lastSweep = now
+	}
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &throttleBucket{
+			tokens:  float64(limit.Burst),
+			updated: now,
+		}
+		store.buckets[key] = bucket
+	}
+
+	bucket.limit = limit
+	bucket.refill(now)
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.exceeded = 0
+
+		return ThrottleResult{Allowed: true}, nil
+	}
+
+	bucket.exceeded++
+
+	return ThrottleResult{
+		RetryAfter: time.Duration((1 - bucket.tokens) * float64(limit.Every)),
+		Exceeded:   bucket.exceeded,
+	}, nil
+}
+
+func (store *ThrottleStoreMemory) sweep(now time.Time) {
+	for key, bucket := range store.buckets {
+		bucket.refill(now)
+
+		if bucket.tokens >= float64(bucket.limit.Burst) {
+			delete(store.buckets, key)
+		}
+	}
+}
+
+func (bucket *throttleBucket) refill(now time.Time) {
+	if bucket.limit.Every > 0 {
+		bucket.tokens += float64(now.Sub(bucket.updated)) / float64(bucket.limit.Every)
+	}
+
+	if burst := float64(bucket.limit.Burst); bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+
+	bucket.updated = now
+}
+
+// ThrottleOption is an option for [Throttle] middleware.
+type ThrottleOption func(*throttle)
+
+type throttle struct {
+	store    ThrottleStore
+	limit    ThrottleLimit
+	keyFunc  func(update *Update) string
+	maxDelay time.Duration
+	reply    string
+}
+
+// WithThrottleKeyFunc sets function to get the throttling key from update.
+// Updates with empty key are not throttled.
+// By default [ThrottleKeyFunc] is used.
+func WithThrottleKeyFunc(keyFunc func(update *Update) string) ThrottleOption {
+	return func(t *throttle) {
+		t.keyFunc = keyFunc
+	}
+}
+
+// WithThrottleDelay enables delaying of updates which exceed the limit, instead of dropping.
+// Update is delayed until token is available, but not longer than maxDelay.
+// If token is not available after delay, update is dropped.
+func WithThrottleDelay(maxDelay time.Duration) ThrottleOption {
+	return func(t *throttle) {
+		t.maxDelay = maxDelay
+	}
+}
+
+// WithThrottleReply sets text which is sent once, when key exceeds the limit.
+// It's sent as answer to callback query or as message to the chat of the update.
+// Next updates are dropped silently, until limit is restored.
+func WithThrottleReply(text string) ThrottleOption {
+	return func(t *throttle) {
+		t.reply = text
+	}
+}
+
+// ThrottleKeyFunc returns key of the user who caused the update.
+// If update has no user (e.g. channel post), the chat key is returned.
+// Bot id is included, so single store can be shared by multiple bots.
+func ThrottleKeyFunc(update *Update) string {
+	if user := getUpdateUser(update); user != nil {
+		return throttleKeyPrefix(update) + "user:" + strconv.FormatInt(int64(user.ID), 10)
+	}
+
+	return ThrottleKeyChatFunc(update)
+}
+
+// ThrottleKeyChatFunc returns key of the chat of the update.
+// It can be used to limit whole group chat, see [WithThrottleKeyFunc].
+func ThrottleKeyChatFunc(update *Update) string {
+	if chat := update.Chat(); chat != nil {
+		return throttleKeyPrefix(update) + "chat:" + strconv.FormatInt(int64(chat.ID), 10)
+	}
+
+	return ""
+}
+
+func throttleKeyPrefix(update *Update) string {
+	var botID string
+	if update.Client != nil {
+		botID, _, _ = strings.Cut(update.Client.Token(), ":")
+	}
+
+	return botID + ":"
+}
+
+// Throttle creates middleware that limits number of updates per user (or other key).
+// Limit is implemented as token bucket, see [ThrottleLimit].
+// Use it with [Router.Group] to protect only expensive handlers.
+//
+// By default, updates which exceed the limit are dropped silently (see [ErrDropUpdate]),
+// use [WithThrottleDelay] and [WithThrottleReply] to change it.
+func Throttle(store ThrottleStore, limit ThrottleLimit, opts ...ThrottleOption) GlobalMiddlewareFunc {
+	t := &throttle{
+		store:   store,
+		limit:   limit,
+		keyFunc: ThrottleKeyFunc,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return func(ctx context.Context, update *Update) (context.Context, *Update, error) {
+		key := t.keyFunc(update)
+		if key == "" {
+			return ctx, update, nil
+		}
+
+		result, err := t.store.Take(ctx, key, t.limit)
+		if err != nil {
+			return ctx, update, fmt.Errorf("throttle: %w", err)
+		}
+
+		// retry of delayed update is not a new excess, so reply is decided by the first result
+		firstExceeded := !result.Allowed && result.Exceeded == 1
+
+		if !result.Allowed && t.maxDelay > 0 && result.RetryAfter <= t.maxDelay {
+			select {
+			case <-time.After(result.RetryAfter):
+			case <-ctx.Done():
+				return ctx, update, ctx.Err()
+			}
+
+			result, err = t.store.Take(ctx, key, t.limit)
+			if err != nil {
+				return ctx, update, fmt.Errorf("throttle: %w", err)
+			}
+		}
+
+		if result.Allowed {
+			return ctx, update, nil
+		}
+
+		if t.reply != "" && firstExceeded {
+			if err := t.sendReply(ctx, update); err != nil {
+				return ctx, update, fmt.Errorf("throttle reply: %w", err)
+			}
+		}
+
+		return ctx, update, ErrDropUpdate
+	}
+}
+
+func (t *throttle) sendReply(ctx context.Context, update *Update) error {
+	if update.CallbackQuery != nil {
+		return update.Reply(ctx, update.Client.AnswerCallbackQuery(update.CallbackQuery.ID).Text(t.reply))
+	}
+
+	if chat := update.Chat(); chat != nil {
+		return update.Reply(ctx, update.Client.SendMessage(chat, t.reply))
+	}
+
+	return nil
+}
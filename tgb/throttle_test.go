@@ -0,0 +1,176 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottleStoreMemory(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := NewThrottleStoreMemory()
+	store.now = func() time.Time { return now }
+
+	limit := ThrottleLimit{Burst: 2, Every: time.Second}
+
+	take := func(key string) ThrottleResult {
+		result, err := store.Take(ctx, key, limit)
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.True(t, take("a").Allowed)
+	assert.True(t, take("a").Allowed)
+	assert.Equal(t, ThrottleResult{RetryAfter: time.Second, Exceeded: 1}, take("a"))
+	assert.Equal(t, 2, take("a").Exceeded)
+
+	assert.True(t, take("b").Allowed, "other key has own bucket")
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, ThrottleResult{RetryAfter: 500 * time.Millisecond, Exceeded: 3}, take("a"))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, take("a").Allowed)
+	assert.Equal(t, 1, take("a").Exceeded, "exceeded is reset after allowed")
+
+	now = now.Add(time.Hour)
+	assert.True(t, take("c").Allowed, "take triggers sweep")
+	assert.Len(t, store.buckets, 1, "full buckets are swept")
+}
+
+type throttleStoreFunc func(ctx context.Context, key string, limit ThrottleLimit) (ThrottleResult, error)
+
+func (f throttleStoreFunc) Take(ctx context.Context, key string, limit ThrottleLimit) (ThrottleResult, error) {
+	return f(ctx, key, limit)
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+
+	var replies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/sendMessage":
+			replies = append(replies, r.FormValue("chat_id")+": "+r.FormValue("text"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`))
+		case "/bot1234:secret/answerCallbackQuery":
+			replies = append(replies, r.FormValue("callback_query_id")+": "+r.FormValue("text"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+
+	newUpdate := func(userID tg.UserID) *Update {
+		return &Update{
+			Client: client,
+			Update: &tg.Update{Message: &tg.Message{
+				Chat: tg.Chat{ID: tg.ChatID(userID)},
+				From: &tg.User{ID: userID},
+			}},
+		}
+	}
+
+	t.Run("KeyFunc", func(t *testing.T) {
+		assert.Equal(t, "1234:user:1", ThrottleKeyFunc(newUpdate(1)))
+		assert.Equal(t, "1234:chat:1", ThrottleKeyChatFunc(newUpdate(1)))
+
+		channelPost := &Update{Update: &tg.Update{ChannelPost: &tg.Message{Chat: tg.Chat{ID: -100}}}}
+		assert.Equal(t, ":chat:-100", ThrottleKeyFunc(channelPost))
+
+		assert.Empty(t, ThrottleKeyFunc(&Update{Update: &tg.Update{Poll: &tg.Poll{}}}))
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		mw := Throttle(NewThrottleStoreMemory(), ThrottleLimit{Burst: 1, Every: time.Hour})
+
+		_, _, err := mw(ctx, newUpdate(1))
+		assert.NoError(t, err)
+
+		_, _, err = mw(ctx, newUpdate(1))
+		assert.ErrorIs(t, err, ErrDropUpdate)
+
+		_, _, err = mw(ctx, newUpdate(2))
+		assert.NoError(t, err)
+
+		assert.Empty(t, replies)
+	})
+
+	t.Run("Reply", func(t *testing.T) {
+		replies = nil
+
+		mw := Throttle(NewThrottleStoreMemory(), ThrottleLimit{Burst: 1, Every: time.Hour}, WithThrottleReply("slow down"))
+
+		for i := 0; i < 3; i++ {
+			_, _, _ = mw(ctx, newUpdate(1))
+		}
+
+		callback := &Update{Client: client, Update: &tg.Update{CallbackQuery: &tg.CallbackQuery{ID: "cq", From: tg.User{ID: 2}}}}
+		for i := 0; i < 3; i++ {
+			_, _, _ = mw(ctx, callback)
+		}
+
+		assert.Equal(t, []string{"1: slow down", "cq: slow down"}, replies)
+	})
+
+	t.Run("Delay", func(t *testing.T) {
+		mw := Throttle(NewThrottleStoreMemory(), ThrottleLimit{Burst: 1, Every: 20 * time.Millisecond}, WithThrottleDelay(time.Second))
+
+		_, _, err := mw(ctx, newUpdate(1))
+		require.NoError(t, err)
+
+		started := time.Now()
+		_, _, err = mw(ctx, newUpdate(1))
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(started), 10*time.Millisecond)
+	})
+
+	t.Run("DelayAndReply", func(t *testing.T) {
+		replies = nil
+
+		// token is taken by other update while delayed update waits
+		var exceeded int
+		store := throttleStoreFunc(func(ctx context.Context, key string, limit ThrottleLimit) (ThrottleResult, error) {
+			exceeded++
+			return ThrottleResult{RetryAfter: time.Millisecond, Exceeded: exceeded}, nil
+		})
+
+		mw := Throttle(store, ThrottleLimit{Burst: 1, Every: time.Hour},
+			WithThrottleDelay(time.Second),
+			WithThrottleReply("slow down"),
+		)
+
+		_, _, err := mw(ctx, newUpdate(1))
+		assert.ErrorIs(t, err, ErrDropUpdate)
+
+		_, _, err = mw(ctx, newUpdate(1))
+		assert.ErrorIs(t, err, ErrDropUpdate)
+
+		assert.Equal(t, []string{"1: slow down"}, replies)
+	})
+
+	t.Run("DelayTooLong", func(t *testing.T) {
+		mw := Throttle(NewThrottleStoreMemory(), ThrottleLimit{Burst: 1, Every: time.Hour}, WithThrottleDelay(time.Second))
+
+		_, _, err := mw(ctx, newUpdate(1))
+		require.NoError(t, err)
+
+		_, _, err = mw(ctx, newUpdate(1))
+		assert.ErrorIs(t, err, ErrDropUpdate)
+	})
+}
@@ -317,3 +317,46 @@ type DeletedBusinessMessagesUpdate struct {
 	Update *Update
 	Client *tg.Client
 }
+
+// getUpdateUser returns user who caused the update.
+// It returns nil if update has no user, e.g. channel post or poll.
+func getUpdateUser(update *Update) *tg.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.EditedMessage != nil:
+		return update.EditedMessage.From
+	case update.ChannelPost != nil:
+		return update.ChannelPost.From
+	case update.EditedChannelPost != nil:
+		return update.EditedChannelPost.From
+	case update.BusinessMessage != nil:
+		return update.BusinessMessage.From
+	case update.EditedBusinessMessage != nil:
+		return update.EditedBusinessMessage.From
+	case update.BusinessConnection != nil:
+		return &update.BusinessConnection.User
+	case update.InlineQuery != nil:
+		return &update.InlineQuery.From
+	case update.ChosenInlineResult != nil:
+		return &update.ChosenInlineResult.From
+	case update.CallbackQuery != nil:
+		return &update.CallbackQuery.From
+	case update.ShippingQuery != nil:
+		return &update.ShippingQuery.From
+	case update.PreCheckoutQuery != nil:
+		return &update.PreCheckoutQuery.From
+	case update.PollAnswer != nil:
+		return update.PollAnswer.User
+	case update.MyChatMember != nil:
+		return &update.MyChatMember.From
+	case update.ChatMember != nil:
+		return &update.ChatMember.From
+	case update.ChatJoinRequest != nil:
+		return &update.ChatJoinRequest.From
+	case update.MessageReaction != nil:
+		return update.MessageReaction.User
+	default:
+		return nil
+	}
+}
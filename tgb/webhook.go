@@ -3,12 +3,15 @@ package tgb
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/netip"
+	"sync"
 	"time"
 
 	tg "github.com/nosefu/go-tg"
@@ -35,6 +38,18 @@ type Webhook struct {
 
 	ipFromRequestFunc func(r *http.Request) string
 
+	tlsSelfSignedHost string
+	tlsCertFile       string
+	tlsKeyFile        string
+
+	tlsLock       sync.Mutex
+	tlsCert       *tls.Certificate
+	tlsCertPEM    []byte
+	tlsCertUpload bool // certificate is not uploaded to Telegram yet
+
+	queue        WebhookQueue
+	queueWorkers int
+
 	isSetup bool
 }
 
@@ -53,7 +68,8 @@ func WithWebhookLogger(logger Logger) WebhookOption {
 	}
 }
 
-// WithDropPendingUpdates drop pending updates (if pending > 0 only)
+// WithDropPendingUpdates drop pending updates (if pending > 0 only).
+// Updates are dropped only on first successful [Webhook.Setup], re-setup by [WebhookMonitor] keeps them.
 func WithDropPendingUpdates(dropPendingUpdates bool) WebhookOption {
 	return func(webhook *Webhook) {
 		webhook.dropPendingUpdates = dropPendingUpdates
@@ -106,12 +122,16 @@ func WithWebhookMaxConnections(maxConnections int) WebhookOption {
 }
 
 // WithWebhookAllowedUpdates sets the list of allowed updates.
-// By default all update types except chat_member (default).
-// If not specified, the previous setting will be used.
+// By default, if handler is [Router], update types with registered handlers are requested,
+// see [Router.AllowedUpdates]. Otherwise all update types except chat_member (default).
 // Please note that this parameter doesn't affect updates created before the call to the setWebhook,
 // so unwanted updates may be received for a short period of time.
 func WithWebhookAllowedUpdates(updates ...tg.UpdateType) WebhookOption {
 	return func(webhook *Webhook) {
+		if updates == nil {
+			updates = []tg.UpdateType{}
+		}
+
 		webhook.allowedUpdates = updates
 	}
 }
@@ -128,11 +148,12 @@ func NewWebhook(handler Handler, client *tg.Client, url string, options ...Webho
 
 		dropPendingUpdates: false,
 
-		allowedUpdates:  []tg.UpdateType{},
 		securitySubnets: defaultSubnets,
 		securityToken:   token,
 
 		ipFromRequestFunc: DefaultWebhookRequestIP,
+
+		queueWorkers: defaultWebhookQueueWorkers,
 	}
 
 	for _, option := range options {
@@ -142,12 +163,40 @@ func NewWebhook(handler Handler, client *tg.Client, url string, options ...Webho
 	return webhook
 }
 
+func (webhook *Webhook) getAllowedUpdates() []tg.UpdateType {
+	if webhook.allowedUpdates != nil {
+		return webhook.allowedUpdates
+	}
+
+	if provider, ok := webhook.handler.(allowedUpdatesProvider); ok {
+		return provider.AllowedUpdates()
+	}
+
+	return []tg.UpdateType{}
+}
+
 func (webhook *Webhook) log(format string, args ...any) {
 	if webhook.logger != nil {
 		webhook.logger.Printf("tgb.Webhook: "+format, args...)
 	}
 }
 
+// handleUpdate calls handler and logs error, panic is logged with stack trace.
+func (webhook *Webhook) handleUpdate(ctx context.Context, update *Update) {
+	err := safeHandle(ctx, webhook.handler, update)
+	if err == nil {
+		return
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		webhook.log("handler panic on update %d: %v\n%s", update.ID, panicErr.Value, panicErr.Stack)
+		return
+	}
+
+	webhook.log("handler error: %v", err)
+}
+
 const defaultMaxConnections = 40
 
 func (webhook *Webhook) Setup(ctx context.Context) (err error) {
@@ -155,16 +204,25 @@ func (webhook *Webhook) Setup(ctx context.Context) (err error) {
 		webhook.isSetup = err == nil
 	}()
 
+	if webhook.isTLS() {
+		if err := webhook.checkPort(); err != nil {
+			return fmt.Errorf("check webhook url: %w", err)
+		}
+
+		if err := webhook.loadCertificate(); err != nil {
+			return fmt.Errorf("load certificate: %w", err)
+		}
+	}
+
 	info, err := webhook.client.GetWebhookInfo().Do(ctx)
 	if err != nil {
 		return fmt.Errorf("get webhook info: %w", err)
 	}
 
-	if info.URL != webhook.url ||
-		info.MaxConnections != webhook.maxConnections ||
-		(len(info.AllowedUpdates) > 0 && !slices.Equal(info.AllowedUpdates, webhook.allowedUpdates)) ||
-		(webhook.ip != "" && info.IPAddress != webhook.ip) ||
-		(info.PendingUpdateCount > 0 && webhook.dropPendingUpdates) {
+	// pending updates are dropped only on first setup, not on re-setup after config drift
+	dropPendingUpdates := webhook.dropPendingUpdates && !webhook.isSetup
+
+	if webhook.isOutdated(&info) || (dropPendingUpdates && info.PendingUpdateCount > 0) {
 
 		webhook.log("current webhook config is outdated, updating...")
 
@@ -182,21 +240,38 @@ func (webhook *Webhook) Setup(ctx context.Context) (err error) {
 			setWebhookCall = setWebhookCall.SecretToken(webhook.securityToken)
 		}
 
-		if webhook.dropPendingUpdates {
+		if dropPendingUpdates {
 			setWebhookCall = setWebhookCall.DropPendingUpdates(true)
 		}
 
-		if webhook.allowedUpdates != nil {
-			setWebhookCall = setWebhookCall.AllowedUpdates(webhook.allowedUpdates)
+		setWebhookCall = setWebhookCall.AllowedUpdates(webhook.getAllowedUpdates())
+
+		if webhook.isTLS() {
+			// generated Certificate method encodes file as JSON, so attach it to request directly
+			setWebhookCall.Request().InputFile("certificate", tg.NewInputFileBytes("certificate.pem", webhook.tlsCertPEM))
 		}
 
-		return setWebhookCall.DoVoid(ctx)
+		if err := setWebhookCall.DoVoid(ctx); err != nil {
+			return err
+		}
+
+		webhook.tlsCertUpload = false
 	}
 
 	return nil
 
 }
 
+// isOutdated checks if webhook config on the Telegram side differs from the local one.
+// Pending updates are not part of config, see [Webhook.Setup].
+func (webhook *Webhook) isOutdated(info *tg.WebhookInfo) bool {
+	return info.URL != webhook.url ||
+		(webhook.isTLS() && (!info.HasCustomCertificate || webhook.tlsCertUpload)) ||
+		info.MaxConnections != webhook.maxConnections ||
+		(len(info.AllowedUpdates) > 0 && !slices.Equal(info.AllowedUpdates, webhook.getAllowedUpdates())) ||
+		(webhook.ip != "" && info.IPAddress != webhook.ip)
+}
+
 func (webhook *Webhook) isAllowedIP(ip netip.Addr) bool {
 	if len(webhook.securitySubnets) == 0 {
 		return true
@@ -288,6 +363,10 @@ func (webhook *Webhook) ServeRequest(ctx context.Context, r *WebhookRequest) *We
 		}
 	}
 
+	if webhook.queue != nil {
+		return webhook.pushToQueue(ctx, baseUpdate)
+	}
+
 	update := newUpdateWebhook(baseUpdate, webhook.client)
 	defer update.disableWebhookReply()
 
@@ -297,10 +376,7 @@ func (webhook *Webhook) ServeRequest(ctx context.Context, r *WebhookRequest) *We
 		handlerCtx, handlerCtxClose := context.WithCancel(context.Background())
 		defer handlerCtxClose()
 
-		// handle update
-		if err := webhook.handler.Handle(handlerCtx, update); err != nil {
-			webhook.log("handler error: %v", err)
-		}
+		webhook.handleUpdate(handlerCtx, update)
 
 		close(done)
 	}()
@@ -335,6 +411,27 @@ func (webhook *Webhook) ServeRequest(ctx context.Context, r *WebhookRequest) *We
 	}
 }
 
+func (webhook *Webhook) pushToQueue(ctx context.Context, update *tg.Update) *WebhookResponse {
+	if err := webhook.queue.Push(ctx, update); err != nil {
+		webhook.log("push update to queue error: %v", err)
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrWebhookQueueFull) || errors.Is(err, ErrWebhookQueueClosed) {
+			status = http.StatusServiceUnavailable
+		}
+
+		return &WebhookResponse{
+			Status:      status,
+			ContentType: "text/plain",
+			Body:        []byte("failed to queue update"),
+		}
+	}
+
+	return &WebhookResponse{
+		Status: http.StatusOK,
+	}
+}
+
 // ServeHTTP is the HTTP handler for webhook requests.
 // Implementation of http.Handler.
 func (webhook *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -370,6 +467,7 @@ func (webhook *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // Run starts the webhook server.
+// In queue mode it also starts workers and waits for queue to drain on shutdown.
 func (webhook *Webhook) Run(ctx context.Context, listen string) error {
 	if !webhook.isSetup {
 		if err := webhook.Setup(ctx); err != nil {
@@ -382,22 +480,61 @@ func (webhook *Webhook) Run(ctx context.Context, listen string) error {
 		Handler: webhook,
 	}
 
+	if webhook.isTLS() {
+		if err := webhook.loadCertificate(); err != nil {
+			return fmt.Errorf("load certificate: %w", err)
+		}
+
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{*webhook.tlsCert},
+		}
+	}
+
+	if webhook.queue != nil {
+		queueCtx, queueCancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			webhook.ProcessQueue(queueCtx)
+		}()
+
+		defer func() {
+			queueCancel()
+			webhook.log("draining queue...")
+			<-done
+		}()
+	}
+
+	webhook.log("starting webhook server on %s", listen)
+
+	return runHTTPServer(ctx, server, webhook.log)
+}
+
+// runHTTPServer serves HTTP until ctx is done, then shutdowns server gracefully.
+// If server.TLSConfig is set, HTTPS is served.
+func runHTTPServer(ctx context.Context, server *http.Server, log func(format string, args ...any)) error {
 	go func() {
 		<-ctx.Done()
 
-		webhook.log("shutdown server...")
+		log("shutdown server...")
 
 		closeCtx, close := context.WithTimeout(context.Background(), 10*time.Second)
 		defer close()
 
 		if err := server.Shutdown(closeCtx); err != nil {
-			webhook.log("server shutdown error: %v", err)
+			log("server shutdown error: %v", err)
 		}
 	}()
 
-	webhook.log("starting webhook server on %s", listen)
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
 
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	if err != http.ErrServerClosed {
 		return fmt.Errorf("server error: %v", err)
 	}
 
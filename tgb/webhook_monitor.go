@@ -0,0 +1,221 @@
+package tgb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookStatus is the webhook health status reported by [WebhookMonitor].
+type WebhookStatus struct {
+	// Healthy is false if Telegram reported delivery error since previous check
+	// or pending updates count exceeds the limit.
+	Healthy bool `json:"healthy"`
+
+	// CheckedAt is the time of the check.
+	CheckedAt time.Time `json:"checked_at"`
+
+	// PendingUpdateCount is the number of updates awaiting delivery.
+	PendingUpdateCount int `json:"pending_update_count"`
+
+	// LastErrorDate is the time of the most recent delivery error, if any.
+	LastErrorDate time.Time `json:"last_error_date"`
+
+	// LastErrorMessage describes the most recent delivery error, if any.
+	LastErrorMessage string `json:"last_error_message,omitempty"`
+
+	// LastSynchronizationErrorDate is the time of the most recent error
+	// that happened when trying to synchronize available updates with Telegram datacenters.
+	LastSynchronizationErrorDate time.Time `json:"last_synchronization_error_date"`
+
+	// Resetup is true if webhook config on the Telegram side was outdated and [Webhook.Setup] was called.
+	Resetup bool `json:"resetup"`
+}
+
+// WebhookMonitor periodically checks webhook info on the Telegram side.
+// Status is reported to callback and can be served as health check endpoint,
+// because WebhookMonitor implements http.Handler.
+// If webhook config on the Telegram side drifts (e.g. URL was changed by other instance),
+// [Webhook.Setup] is called again.
+type WebhookMonitor struct {
+	webhook *Webhook
+	logger  Logger
+
+	interval   time.Duration
+	maxPending int
+	onStatus   func(ctx context.Context, status *WebhookStatus)
+
+	lock      sync.RWMutex
+	status    *WebhookStatus
+	lastCheck time.Time
+}
+
+// WebhookMonitorOption used to configure the WebhookMonitor.
+type WebhookMonitorOption func(*WebhookMonitor)
+
+// WithWebhookMonitorInterval sets the interval between checks.
+// By default is 1 minute.
+func WithWebhookMonitorInterval(interval time.Duration) WebhookMonitorOption {
+	return func(monitor *WebhookMonitor) {
+		monitor.interval = interval
+	}
+}
+
+// WithWebhookMonitorMaxPending sets the maximum pending updates count for healthy webhook.
+// By default is 100. Zero disables the check.
+func WithWebhookMonitorMaxPending(maxPending int) WebhookMonitorOption {
+	return func(monitor *WebhookMonitor) {
+		monitor.maxPending = maxPending
+	}
+}
+
+// WithWebhookMonitorOnStatus sets the callback which is called after each check.
+func WithWebhookMonitorOnStatus(onStatus func(ctx context.Context, status *WebhookStatus)) WebhookMonitorOption {
+	return func(monitor *WebhookMonitor) {
+		monitor.onStatus = onStatus
+	}
+}
+
+// WithWebhookMonitorLogger sets the logger which will be used to log the monitor related errors.
+func WithWebhookMonitorLogger(logger Logger) WebhookMonitorOption {
+	return func(monitor *WebhookMonitor) {
+		monitor.logger = logger
+	}
+}
+
+// NewWebhookMonitor creates a new WebhookMonitor for specified webhook.
+func NewWebhookMonitor(webhook *Webhook, options ...WebhookMonitorOption) *WebhookMonitor {
+	monitor := &WebhookMonitor{
+		webhook:    webhook,
+		interval:   time.Minute,
+		maxPending: 100,
+	}
+
+	for _, option := range options {
+		option(monitor)
+	}
+
+	return monitor
+}
+
+func (monitor *WebhookMonitor) log(format string, args ...any) {
+	if monitor.logger != nil {
+		monitor.logger.Printf("tgb.WebhookMonitor: "+format, args...)
+	}
+}
+
+// Check fetches webhook info, re-runs [Webhook.Setup] if config is outdated and returns status.
+func (monitor *WebhookMonitor) Check(ctx context.Context) (*WebhookStatus, error) {
+	info, err := monitor.webhook.client.GetWebhookInfo().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get webhook info: %w", err)
+	}
+
+	now := time.Now()
+
+	monitor.lock.RLock()
+	lastCheck := monitor.lastCheck
+	monitor.lock.RUnlock()
+
+	status := &WebhookStatus{
+		Healthy:            true,
+		CheckedAt:          now,
+		PendingUpdateCount: info.PendingUpdateCount,
+		LastErrorMessage:   info.LastErrorMessage,
+	}
+
+	if info.LastErrorDate != 0 {
+		status.LastErrorDate = info.LastErrorDateTime()
+
+		// first check takes into account errors for one interval
+		if lastCheck.IsZero() {
+			lastCheck = now.Add(-monitor.interval)
+		}
+
+		if status.LastErrorDate.After(lastCheck) {
+			status.Healthy = false
+		}
+	}
+
+	if info.LastSynchronizationErrorDate != 0 {
+		status.LastSynchronizationErrorDate = info.LastSynchronizationErrorDateTime()
+	}
+
+	if monitor.maxPending > 0 && info.PendingUpdateCount > monitor.maxPending {
+		status.Healthy = false
+	}
+
+	if monitor.webhook.isOutdated(&info) {
+		monitor.log("webhook config drift detected, running setup...")
+
+		if err := monitor.webhook.Setup(ctx); err != nil {
+			return nil, fmt.Errorf("setup webhook: %w", err)
+		}
+
+		status.Resetup = true
+	}
+
+	monitor.lock.Lock()
+	monitor.status = status
+	monitor.lastCheck = now
+	monitor.lock.Unlock()
+
+	if monitor.onStatus != nil {
+		monitor.onStatus(ctx, status)
+	}
+
+	return status, nil
+}
+
+// Status returns the status of the last check.
+// Returns nil if no checks were made.
+func (monitor *WebhookMonitor) Status() *WebhookStatus {
+	monitor.lock.RLock()
+	defer monitor.lock.RUnlock()
+
+	return monitor.status
+}
+
+// Run checks webhook periodically until ctx is done.
+func (monitor *WebhookMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(monitor.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := monitor.Check(ctx); err != nil && ctx.Err() == nil {
+			monitor.log("check error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP serves status of the last check as JSON.
+// Responds with 503 if webhook is not healthy or no checks were made.
+// Implementation of http.Handler.
+func (monitor *WebhookMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := monitor.Status()
+
+	code := http.StatusOK
+	if status == nil || !status.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		monitor.log("marshal status error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,167 @@
+package tgb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookMonitor(t *testing.T) {
+	var (
+		info          string
+		setWebhookHit int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":` + info + `}`))
+		case "/bot1234:secret/setWebhook":
+			setWebhookHit++
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(
+		HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+		tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+		"https://example.com/webhook",
+	)
+
+	var statuses []*WebhookStatus
+
+	monitor := NewWebhookMonitor(webhook,
+		WithWebhookMonitorInterval(time.Minute),
+		WithWebhookMonitorMaxPending(10),
+		WithWebhookMonitorOnStatus(func(ctx context.Context, status *WebhookStatus) {
+			statuses = append(statuses, status)
+		}),
+	)
+
+	serve := func() (int, *WebhookStatus) {
+		w := httptest.NewRecorder()
+		monitor.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var status *WebhookStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+
+		return w.Code, status
+	}
+
+	code, _ := serve()
+	assert.Equal(t, http.StatusServiceUnavailable, code, "no checks yet")
+
+	t.Run("Error", func(t *testing.T) {
+		info = fmt.Sprintf(
+			`{"url":"https://example.com/webhook","max_connections":40,"pending_update_count":3,"last_error_date":%d,"last_error_message":"Connection refused"}`,
+			time.Now().Add(-time.Second*10).Unix(),
+		)
+
+		status, err := monitor.Check(context.Background())
+		require.NoError(t, err)
+
+		assert.False(t, status.Healthy)
+		assert.False(t, status.Resetup)
+		assert.Equal(t, 3, status.PendingUpdateCount)
+		assert.Equal(t, "Connection refused", status.LastErrorMessage)
+		assert.Equal(t, 0, setWebhookHit)
+
+		code, served := serve()
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "Connection refused", served.LastErrorMessage)
+	})
+
+	t.Run("Recovered", func(t *testing.T) {
+		// same error is not reported twice
+		status, err := monitor.Check(context.Background())
+		require.NoError(t, err)
+		assert.True(t, status.Healthy)
+
+		code, _ := serve()
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("TooManyPending", func(t *testing.T) {
+		info = `{"url":"https://example.com/webhook","max_connections":40,"pending_update_count":11}`
+
+		status, err := monitor.Check(context.Background())
+		require.NoError(t, err)
+		assert.False(t, status.Healthy)
+	})
+
+	t.Run("Drift", func(t *testing.T) {
+		info = `{"url":"https://other.com/webhook","max_connections":40}`
+
+		status, err := monitor.Check(context.Background())
+		require.NoError(t, err)
+
+		assert.True(t, status.Resetup)
+		assert.Equal(t, 1, setWebhookHit)
+	})
+
+	assert.Len(t, statuses, 4)
+	assert.Equal(t, statuses[len(statuses)-1], monitor.Status())
+}
+
+func TestWebhookMonitor_DropPendingUpdates(t *testing.T) {
+	var (
+		info  string
+		drops []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":` + info + `}`))
+		case "/bot1234:secret/setWebhook":
+			drops = append(drops, r.FormValue("drop_pending_updates"))
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(
+		HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+		tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+		"https://example.com/webhook",
+		WithDropPendingUpdates(true),
+	)
+
+	monitor := NewWebhookMonitor(webhook)
+
+	// pending updates are dropped on startup
+	info = `{"url":"https://example.com/webhook","max_connections":40,"pending_update_count":3}`
+	require.NoError(t, webhook.Setup(context.Background()))
+	assert.Equal(t, []string{"true"}, drops)
+
+	// normal backlog is not a drift
+	info = `{"url":"https://example.com/webhook","max_connections":40,"pending_update_count":5}`
+	status, err := monitor.Check(context.Background())
+	require.NoError(t, err)
+	assert.False(t, status.Resetup)
+	assert.Equal(t, []string{"true"}, drops, "setWebhook should not be called")
+
+	// re-setup after drift keeps pending updates
+	info = `{"url":"https://other.com/webhook","max_connections":40,"pending_update_count":5}`
+	status, err = monitor.Check(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Resetup)
+	assert.Equal(t, []string{"true", ""}, drops)
+}
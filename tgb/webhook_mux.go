@@ -0,0 +1,293 @@
+package tgb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	tg "github.com/nosefu/go-tg"
+)
+
+// WebhookMux serves webhooks of multiple bots on single listener.
+// Each bot has own URL with path segment derived from the bot token hash,
+// so the token itself is never exposed.
+// Requests are routed by the last path segment, then handled by [Webhook] of the bot
+// with all security checks (IP subnets and secret token).
+//
+// Bots can be added and removed at runtime, see [WebhookMux.Add] and [WebhookMux.Remove].
+type WebhookMux struct {
+	url    string
+	logger Logger
+
+	pathFunc func(client *tg.Client) string
+	options  []WebhookOption
+
+	webhooksLock sync.RWMutex
+	webhooks     map[string]*Webhook
+
+	// queues of webhooks in queue mode, processed while mux is running
+	queuesCtx     context.Context
+	queuesCancels map[*Webhook]context.CancelFunc
+	queuesWG      sync.WaitGroup
+}
+
+// WebhookMuxOption used to configure the WebhookMux.
+type WebhookMuxOption func(*WebhookMux)
+
+// WithWebhookMuxLogger sets the logger which will be used to log the mux related errors.
+// It's also used by webhooks, if not overridden by [WithWebhookLogger].
+func WithWebhookMuxLogger(logger Logger) WebhookMuxOption {
+	return func(mux *WebhookMux) {
+		mux.logger = logger
+	}
+}
+
+// WithWebhookMuxPathFunc sets function to get the URL path segment of the bot.
+// Segment should be unique per bot and should not contain '/'.
+// By default [DefaultWebhookMuxPath] is used.
+func WithWebhookMuxPathFunc(pathFunc func(client *tg.Client) string) WebhookMuxOption {
+	return func(mux *WebhookMux) {
+		mux.pathFunc = pathFunc
+	}
+}
+
+// WithWebhookMuxOptions sets the default options for all webhooks added to mux.
+// Options passed to [WebhookMux.Add] are applied after defaults.
+// Don't pass [WithWebhookQueue] here, queue can't be shared between bots.
+func WithWebhookMuxOptions(options ...WebhookOption) WebhookMuxOption {
+	return func(mux *WebhookMux) {
+		mux.options = options
+	}
+}
+
+// DefaultWebhookMuxPath returns path segment of the bot generated from the client token via sha256.
+// It differs from the default security token of [Webhook].
+func DefaultWebhookMuxPath(client *tg.Client) string {
+	hash := sha256.Sum256([]byte("tgb.WebhookMux:" + client.Token()))
+	return hex.EncodeToString(hash[:16])
+}
+
+// NewWebhookMux creates a new WebhookMux.
+// baseURL is the public URL of the mux, bot URLs are built as baseURL + "/" + path segment.
+func NewWebhookMux(baseURL string, options ...WebhookMuxOption) *WebhookMux {
+	mux := &WebhookMux{
+		url:      strings.TrimSuffix(baseURL, "/"),
+		pathFunc: DefaultWebhookMuxPath,
+		webhooks: make(map[string]*Webhook),
+
+		queuesCancels: make(map[*Webhook]context.CancelFunc),
+	}
+
+	for _, option := range options {
+		option(mux)
+	}
+
+	return mux
+}
+
+func (mux *WebhookMux) log(format string, args ...any) {
+	if mux.logger != nil {
+		mux.logger.Printf("tgb.WebhookMux: "+format, args...)
+	}
+}
+
+// Add registers bot in the mux and calls [Webhook.Setup] for it.
+// If bot with the same path is already registered, it will be replaced.
+// Returns created [Webhook].
+//
+// Each bot in queue mode needs own queue (see [WithWebhookQueue]),
+// [ErrWebhookQueueShared] is returned if queue is already used by other bot.
+func (mux *WebhookMux) Add(ctx context.Context, handler Handler, client *tg.Client, options ...WebhookOption) (*Webhook, error) {
+	segment := mux.pathFunc(client)
+
+	opts := make([]WebhookOption, 0, len(mux.options)+len(options)+1)
+	if mux.logger != nil {
+		opts = append(opts, WithWebhookLogger(mux.logger))
+	}
+	opts = append(opts, mux.options...)
+	opts = append(opts, options...)
+
+	webhook := NewWebhook(handler, client, mux.url+"/"+segment, opts...)
+
+	// register before setup, Telegram can deliver updates right after setWebhook
+	mux.webhooksLock.Lock()
+	if mux.isQueueUsed(webhook.queue) {
+		mux.webhooksLock.Unlock()
+		return nil, ErrWebhookQueueShared
+	}
+	previous, replaced := mux.webhooks[segment]
+	mux.webhooks[segment] = webhook
+	mux.startQueue(webhook)
+	mux.webhooksLock.Unlock()
+
+	if err := webhook.Setup(ctx); err != nil {
+		mux.webhooksLock.Lock()
+		if mux.webhooks[segment] == webhook {
+			mux.stopQueue(webhook)
+
+			// queue of previous webhook is not stopped yet, so it keeps working
+			if replaced {
+				mux.webhooks[segment] = previous
+			} else {
+				delete(mux.webhooks, segment)
+			}
+		}
+		mux.webhooksLock.Unlock()
+
+		return nil, fmt.Errorf("setup webhook: %w", err)
+	}
+
+	if replaced {
+		mux.webhooksLock.Lock()
+		mux.stopQueue(previous)
+		mux.webhooksLock.Unlock()
+	}
+
+	return webhook, nil
+}
+
+// isQueueUsed checks if queue is used by any registered webhook.
+// Should be called with webhooksLock held.
+func (mux *WebhookMux) isQueueUsed(queue WebhookQueue) bool {
+	if queue == nil || !reflect.TypeOf(queue).Comparable() {
+		return false
+	}
+
+	for _, webhook := range mux.webhooks {
+		if webhook.queue != nil && reflect.TypeOf(webhook.queue).Comparable() && webhook.queue == queue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// startQueue starts processing of the webhook queue, if mux is running and queue mode is enabled.
+// Should be called with webhooksLock held.
+func (mux *WebhookMux) startQueue(webhook *Webhook) {
+	if webhook.queue == nil || mux.queuesCtx == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(mux.queuesCtx)
+	mux.queuesCancels[webhook] = cancel
+
+	mux.queuesWG.Add(1)
+
+	go func() {
+		defer mux.queuesWG.Done()
+		webhook.ProcessQueue(ctx)
+	}()
+}
+
+// stopQueue stops processing of the webhook queue, remaining updates are drained in background.
+// Should be called with webhooksLock held.
+func (mux *WebhookMux) stopQueue(webhook *Webhook) {
+	if cancel, ok := mux.queuesCancels[webhook]; ok {
+		cancel()
+		delete(mux.queuesCancels, webhook)
+	}
+}
+
+// Remove unregisters bot from the mux.
+// Returns false if bot is not registered.
+// Queue of the bot (see [WithWebhookQueue]) is closed and drained.
+//
+// Webhook on the Telegram side is not deleted,
+// call DeleteWebhook if bot should stop receiving updates.
+func (mux *WebhookMux) Remove(client *tg.Client) bool {
+	segment := mux.pathFunc(client)
+
+	mux.webhooksLock.Lock()
+	defer mux.webhooksLock.Unlock()
+
+	webhook, ok := mux.webhooks[segment]
+	if !ok {
+		return false
+	}
+
+	mux.stopQueue(webhook)
+	delete(mux.webhooks, segment)
+
+	return true
+}
+
+// Setup calls [Webhook.Setup] for each registered bot.
+func (mux *WebhookMux) Setup(ctx context.Context) error {
+	mux.webhooksLock.RLock()
+	webhooks := make(map[string]*Webhook, len(mux.webhooks))
+	for segment, webhook := range mux.webhooks {
+		webhooks[segment] = webhook
+	}
+	mux.webhooksLock.RUnlock()
+
+	for segment, webhook := range webhooks {
+		if err := webhook.Setup(ctx); err != nil {
+			return fmt.Errorf("setup webhook '%s': %w", segment, err)
+		}
+	}
+
+	return nil
+}
+
+func (mux *WebhookMux) get(segment string) *Webhook {
+	mux.webhooksLock.RLock()
+	defer mux.webhooksLock.RUnlock()
+
+	return mux.webhooks[segment]
+}
+
+// ServeHTTP routes webhook request to the bot.
+// Implementation of http.Handler.
+func (mux *WebhookMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segment := path.Base(r.URL.Path)
+
+	webhook := mux.get(segment)
+	if webhook == nil {
+		mux.log("request to unknown path '%s' was refused", r.URL.Path)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	webhook.ServeHTTP(w, r)
+}
+
+// Run starts the webhook server.
+// Bots should be added before or while server is running.
+// Queues of bots in queue mode (see [WithWebhookQueue]) are processed while server is running
+// and drained after shutdown.
+func (mux *WebhookMux) Run(ctx context.Context, listen string) error {
+	server := &http.Server{
+		Addr:    listen,
+		Handler: mux,
+	}
+
+	mux.webhooksLock.Lock()
+	queuesCtx, queuesCancel := context.WithCancel(context.Background())
+	mux.queuesCtx = queuesCtx
+	for _, webhook := range mux.webhooks {
+		mux.startQueue(webhook)
+	}
+	mux.webhooksLock.Unlock()
+
+	defer func() {
+		mux.webhooksLock.Lock()
+		queuesCancel()
+		mux.queuesCtx = nil
+		mux.queuesCancels = make(map[*Webhook]context.CancelFunc)
+		mux.webhooksLock.Unlock()
+
+		mux.log("draining queues...")
+		mux.queuesWG.Wait()
+	}()
+
+	mux.log("starting webhook server on %s", listen)
+
+	return runHTTPServer(ctx, server, mux.log)
+}
@@ -0,0 +1,340 @@
+package tgb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookMux(t *testing.T) {
+	var (
+		lock sync.Mutex
+		urls = map[string]string{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		token, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+
+		switch method {
+		case "getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "setWebhook":
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			vs, err := url.ParseQuery(string(body))
+			assert.NoError(t, err)
+
+			lock.Lock()
+			urls[token] = vs.Get("url")
+			lock.Unlock()
+
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(token string) *tg.Client {
+		return tg.New(token, tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+	}
+
+	var handled []string
+
+	newHandler := func(name string) Handler {
+		return HandlerFunc(func(ctx context.Context, update *Update) error {
+			lock.Lock()
+			handled = append(handled, name)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	mux := NewWebhookMux("https://example.com/webhook/",
+		WithWebhookMuxOptions(WithWebhookSecuritySubnets()),
+	)
+
+	clientA := newClient("1234:secret")
+	clientB := newClient("5678:secret")
+
+	webhookA, err := mux.Add(context.Background(), newHandler("a"), clientA)
+	require.NoError(t, err)
+
+	_, err = mux.Add(context.Background(), newHandler("b"), clientB)
+	require.NoError(t, err)
+
+	require.NoError(t, mux.Setup(context.Background()))
+
+	assert.Equal(t, "https://example.com/webhook/"+DefaultWebhookMuxPath(clientA), urls["1234:secret"])
+	assert.Equal(t, "https://example.com/webhook/"+DefaultWebhookMuxPath(clientB), urls["5678:secret"])
+	assert.Equal(t, "https://example.com/webhook/"+DefaultWebhookMuxPath(clientA), webhookA.url)
+	assert.NotEqual(t, DefaultWebhookMuxPath(clientA), DefaultWebhookMuxPath(clientB))
+
+	securityToken := func(token string) string {
+		hash := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(hash[:])
+	}
+
+	do := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"update_id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "1.1.1.1")
+		req.Header.Set(securityTokenHeader, token)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	pathA := "/webhook/" + DefaultWebhookMuxPath(clientA)
+	pathB := "/webhook/" + DefaultWebhookMuxPath(clientB)
+
+	assert.Equal(t, http.StatusOK, do(pathA, securityToken("1234:secret")))
+	assert.Equal(t, http.StatusOK, do(pathB, securityToken("5678:secret")))
+	assert.Equal(t, []string{"a", "b"}, handled)
+
+	assert.Equal(t, http.StatusForbidden, do(pathA, securityToken("5678:secret")), "token of other bot")
+	assert.Equal(t, http.StatusNotFound, do("/webhook/unknown", securityToken("1234:secret")))
+
+	assert.True(t, mux.Remove(clientA))
+	assert.False(t, mux.Remove(clientA))
+	assert.Equal(t, http.StatusNotFound, do(pathA, securityToken("1234:secret")))
+	assert.Equal(t, http.StatusOK, do(pathB, securityToken("5678:secret")))
+}
+
+func TestWebhookMux_Add(t *testing.T) {
+	var (
+		mux        *WebhookMux
+		registered []bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		token, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+		client := tg.New(token)
+
+		switch method {
+		case "getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "setWebhook":
+			registered = append(registered, mux.get(DefaultWebhookMuxPath(client)) != nil)
+
+			if token == "5678:secret" {
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: bad webhook"}`))
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(token string) *tg.Client {
+		return tg.New(token, tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+	}
+
+	handler := HandlerFunc(func(ctx context.Context, update *Update) error { return nil })
+
+	mux = NewWebhookMux("https://example.com/webhook")
+
+	clientA := newClient("1234:secret")
+	clientB := newClient("5678:secret")
+
+	_, err := mux.Add(context.Background(), handler, clientA)
+	require.NoError(t, err)
+
+	_, err = mux.Add(context.Background(), handler, clientB)
+	require.Error(t, err)
+
+	assert.Equal(t, []bool{true, true}, registered, "registered before setWebhook")
+	assert.NotNil(t, mux.get(DefaultWebhookMuxPath(clientA)))
+	assert.Nil(t, mux.get(DefaultWebhookMuxPath(clientB)), "removed after failed setup")
+}
+
+func TestWebhookMux_Queue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch path.Base(r.URL.Path) {
+		case "getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "setWebhook":
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+
+	handled := make(chan int, 1)
+
+	mux := NewWebhookMux("https://example.com/webhook",
+		WithWebhookMuxOptions(WithWebhookSecuritySubnets(), WithWebhookSecurityToken("")),
+	)
+
+	_, err := mux.Add(context.Background(), HandlerFunc(func(ctx context.Context, update *Update) error {
+		handled <- update.ID
+		return nil
+	}), client, WithWebhookQueue(NewWebhookQueueMemory(1)), WithWebhookQueueWorkers(1))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- mux.Run(ctx, "127.0.0.1:0")
+	}()
+
+	require.Eventually(t, func() bool {
+		mux.webhooksLock.RLock()
+		defer mux.webhooksLock.RUnlock()
+		return len(mux.queuesCancels) == 1
+	}, time.Second, time.Millisecond*10)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/"+DefaultWebhookMuxPath(client), strings.NewReader(`{"update_id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, 1, id)
+	case <-time.After(time.Second):
+		t.Fatal("update from queue is not handled")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWebhookMux_QueueReplaceFailed(t *testing.T) {
+	var failSetWebhook bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch path.Base(r.URL.Path) {
+		case "getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "setWebhook":
+			if failSetWebhook {
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: bad webhook"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+
+	handled := make(chan int, 1)
+
+	handler := HandlerFunc(func(ctx context.Context, update *Update) error {
+		handled <- update.ID
+		return nil
+	})
+
+	mux := NewWebhookMux("https://example.com/webhook",
+		WithWebhookMuxOptions(WithWebhookSecuritySubnets(), WithWebhookSecurityToken("")),
+	)
+
+	_, err := mux.Add(context.Background(), handler, client, WithWebhookQueue(NewWebhookQueueMemory(1)), WithWebhookQueueWorkers(1))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- mux.Run(ctx, "127.0.0.1:0")
+	}()
+
+	require.Eventually(t, func() bool {
+		mux.webhooksLock.RLock()
+		defer mux.webhooksLock.RUnlock()
+		return len(mux.queuesCancels) == 1
+	}, time.Second, time.Millisecond*10)
+
+	// replace fails, previous webhook is restored with working queue
+	failSetWebhook = true
+	_, err = mux.Add(context.Background(), handler, client, WithWebhookQueue(NewWebhookQueueMemory(1)), WithWebhookQueueWorkers(1))
+	require.Error(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/"+DefaultWebhookMuxPath(client), strings.NewReader(`{"update_id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, 1, id)
+	case <-time.After(time.Second):
+		t.Fatal("update from queue is not handled")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWebhookMux_QueueShared(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch path.Base(r.URL.Path) {
+		case "getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":""}}`))
+		case "setWebhook":
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(token string) *tg.Client {
+		return tg.New(token, tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client()))
+	}
+
+	handler := HandlerFunc(func(ctx context.Context, update *Update) error { return nil })
+
+	mux := NewWebhookMux("https://example.com/webhook",
+		WithWebhookMuxOptions(WithWebhookQueue(NewWebhookQueueMemory(1))),
+	)
+
+	_, err := mux.Add(context.Background(), handler, newClient("1234:secret"))
+	require.NoError(t, err)
+
+	_, err = mux.Add(context.Background(), handler, newClient("5678:secret"))
+	assert.ErrorIs(t, err, ErrWebhookQueueShared)
+
+	// own queue is fine
+	_, err = mux.Add(context.Background(), handler, newClient("5678:secret"), WithWebhookQueue(NewWebhookQueueMemory(1)))
+	assert.NoError(t, err)
+}
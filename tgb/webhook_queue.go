@@ -0,0 +1,200 @@
+package tgb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+)
+
+var (
+	// ErrWebhookQueueFull is returned by [WebhookQueue.Push] when queue is full.
+	ErrWebhookQueueFull = errors.New("webhook queue is full")
+
+	// ErrWebhookQueueShared is returned by [WebhookMux.Add] when queue is already used by other bot.
+	ErrWebhookQueueShared = errors.New("webhook queue is already used by other bot")
+
+	// ErrWebhookQueueClosed is returned by [WebhookQueue] when queue is closed.
+	// [WebhookQueue.Pop] returns it only after all queued updates are popped.
+	ErrWebhookQueueClosed = errors.New("webhook queue is closed")
+)
+
+// WebhookQueue define interface for queue of updates received by Webhook.
+// See [WebhookQueueMemory] for example.
+type WebhookQueue interface {
+	// Push adds update to queue without blocking.
+	// Returns [ErrWebhookQueueFull] if queue is full.
+	Push(ctx context.Context, update *tg.Update) error
+
+	// Pop returns next update from queue, blocks until update is available.
+	// Returns [ErrWebhookQueueClosed] if queue is closed and empty.
+	Pop(ctx context.Context) (*tg.Update, error)
+
+	// Close closes the queue. Updates pushed before close still can be popped.
+	Close() error
+}
+
+// WebhookQueueMemory is a bounded in-memory queue of updates.
+// It implements [WebhookQueue] and is thread-safe.
+type WebhookQueueMemory struct {
+	updates chan *tg.Update
+
+	lock   sync.RWMutex
+	closed bool
+}
+
+var _ WebhookQueue = (*WebhookQueueMemory)(nil)
+
+// NewWebhookQueueMemory creates in-memory queue with specified capacity.
+func NewWebhookQueueMemory(size int) *WebhookQueueMemory {
+	return &WebhookQueueMemory{
+		updates: make(chan *tg.Update, size),
+	}
+}
+
+func (queue *WebhookQueueMemory) Push(ctx context.Context, update *tg.Update) error {
+	queue.lock.RLock()
+	defer queue.lock.RUnlock()
+
+	if queue.closed {
+		return ErrWebhookQueueClosed
+	}
+
+	select {
+	case queue.updates <- update:
+		return nil
+	default:
+		return ErrWebhookQueueFull
+	}
+}
+
+func (queue *WebhookQueueMemory) Pop(ctx context.Context) (*tg.Update, error) {
+	select {
+	case update, ok := <-queue.updates:
+		if !ok {
+			return nil, ErrWebhookQueueClosed
+		}
+		return update, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (queue *WebhookQueueMemory) Close() error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	if !queue.closed {
+		queue.closed = true
+		close(queue.updates)
+	}
+
+	return nil
+}
+
+// WithWebhookQueue enables queue mode.
+// In this mode update is pushed to the queue and request is responded immediately,
+// so slow handlers does not delay webhook delivery.
+// If queue is full, request is responded with 503 and Telegram will retry it later.
+//
+// Updates are handled by workers started with [Webhook.ProcessQueue] (called by [Webhook.Run] and [WebhookMux.Run]).
+// Reply to webhook is not available in this mode, [Update.Reply] makes regular call.
+// Queue is owned by the webhook and closed on shutdown, so it can't be shared between webhooks.
+func WithWebhookQueue(queue WebhookQueue) WebhookOption {
+	return func(webhook *Webhook) {
+		webhook.queue = queue
+	}
+}
+
+// WithWebhookQueueWorkers sets the number of workers handling updates from queue.
+// By default is 8.
+func WithWebhookQueueWorkers(workers int) WebhookOption {
+	return func(webhook *Webhook) {
+		webhook.queueWorkers = workers
+	}
+}
+
+const defaultWebhookQueueWorkers = 8
+
+const (
+	webhookQueueMinBackoff = 100 * time.Millisecond
+	webhookQueueMaxBackoff = 10 * time.Second
+)
+
+// webhookQueueBackoff returns delay before next pop after specified number of consecutive fails.
+func webhookQueueBackoff(fails int) time.Duration {
+	delay := webhookQueueMinBackoff
+	for i := 0; i < fails && delay < webhookQueueMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > webhookQueueMaxBackoff {
+		delay = webhookQueueMaxBackoff
+	}
+
+	return delay
+}
+
+// ProcessQueue handles updates from queue until ctx is done.
+// After that queue is closed and remaining updates are drained.
+// If queue returns error, workers retry with backoff, after ctx is done workers stop on first error.
+// It's called by [Webhook.Run], call it manually only when webhook is served by other server.
+func (webhook *Webhook) ProcessQueue(ctx context.Context) {
+	if webhook.queue == nil {
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		if err := webhook.queue.Close(); err != nil {
+			webhook.log("close queue error: %v", err)
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < webhook.queueWorkers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var fails int
+
+			for {
+				update, err := webhook.queue.Pop(context.Background())
+				if errors.Is(err, ErrWebhookQueueClosed) {
+					return
+				} else if err != nil {
+					webhook.log("pop update from queue error: %v", err)
+
+					// don't drain queue with failing backend
+					if ctx.Err() != nil {
+						return
+					}
+
+					select {
+					case <-time.After(webhookQueueBackoff(fails)):
+					case <-ctx.Done():
+					}
+
+					fails++
+
+					continue
+				}
+
+				fails = 0
+
+				webhook.handleUpdate(context.Background(), &Update{
+					Update: update,
+					Client: webhook.client,
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,156 @@
+package tgb
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookQueueMemory(t *testing.T) {
+	ctx := context.Background()
+
+	queue := NewWebhookQueueMemory(2)
+
+	require.NoError(t, queue.Push(ctx, &tg.Update{ID: 1}))
+	require.NoError(t, queue.Push(ctx, &tg.Update{ID: 2}))
+	assert.ErrorIs(t, queue.Push(ctx, &tg.Update{ID: 3}), ErrWebhookQueueFull)
+
+	update, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, update.ID)
+
+	require.NoError(t, queue.Close())
+	require.NoError(t, queue.Close())
+
+	assert.ErrorIs(t, queue.Push(ctx, &tg.Update{ID: 4}), ErrWebhookQueueClosed)
+
+	update, err = queue.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, update.ID)
+
+	_, err = queue.Pop(ctx)
+	assert.ErrorIs(t, err, ErrWebhookQueueClosed)
+
+	t.Run("PopCanceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewWebhookQueueMemory(1).Pop(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestWebhook_Queue(t *testing.T) {
+	newRequest := func(id int) *WebhookRequest {
+		return &WebhookRequest{
+			Method:      http.MethodPost,
+			ContentType: "application/json",
+			IP:          netip.MustParseAddr("1.1.1.1"),
+			Body:        strings.NewReader(`{"update_id":` + strconv.Itoa(id) + `}`),
+		}
+	}
+
+	var (
+		handled int32
+		release = make(chan struct{})
+	)
+
+	webhook := NewWebhook(
+		HandlerFunc(func(ctx context.Context, update *Update) error {
+			<-release
+			atomic.AddInt32(&handled, 1)
+			return nil
+		}),
+		&tg.Client{},
+		"https://example.com/webhook",
+		WithWebhookSecuritySubnets(),
+		WithWebhookSecurityToken(""),
+		WithWebhookQueue(NewWebhookQueueMemory(2)),
+		WithWebhookQueueWorkers(1),
+	)
+
+	// requests are responded before handler finishes
+	for i := 1; i <= 2; i++ {
+		res := webhook.ServeRequest(context.Background(), newRequest(i))
+		assert.Equal(t, http.StatusOK, res.Status)
+	}
+
+	// queue is full
+	res := webhook.ServeRequest(context.Background(), newRequest(3))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Status)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		webhook.ProcessQueue(ctx)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+		t.Fatal("ProcessQueue should wait for queue drain")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	<-done
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&handled))
+
+	// queue is closed after shutdown
+	res = webhook.ServeRequest(context.Background(), newRequest(4))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Status)
+}
+
+type testWebhookQueueFailing struct {
+	pops int32
+}
+
+func (queue *testWebhookQueueFailing) Push(ctx context.Context, update *tg.Update) error {
+	return nil
+}
+
+func (queue *testWebhookQueueFailing) Pop(ctx context.Context) (*tg.Update, error) {
+	atomic.AddInt32(&queue.pops, 1)
+	return nil, assert.AnError
+}
+
+func (queue *testWebhookQueueFailing) Close() error {
+	return nil
+}
+
+func TestWebhook_QueueError(t *testing.T) {
+	queue := &testWebhookQueueFailing{}
+
+	webhook := NewWebhook(
+		HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+		&tg.Client{},
+		"https://example.com/webhook",
+		WithWebhookQueue(queue),
+		WithWebhookQueueWorkers(1),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*250)
+	defer cancel()
+
+	webhook.ProcessQueue(ctx)
+
+	// 0ms, 100ms, 300ms with backoff
+	assert.LessOrEqual(t, atomic.LoadInt32(&queue.pops), int32(3))
+
+	assert.Equal(t, webhookQueueMinBackoff, webhookQueueBackoff(0))
+	assert.Equal(t, webhookQueueMinBackoff*4, webhookQueueBackoff(2))
+	assert.Equal(t, webhookQueueMaxBackoff, webhookQueueBackoff(100))
+}
@@ -425,6 +425,46 @@ func TestWebhook_Setup(t *testing.T) {
 
 }
 
+func TestWebhook_SetupAllowedUpdates(t *testing.T) {
+	var allowedUpdates string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/bot1234:secret/getWebhookInfo":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"url":"https://google.com","max_connections":40,"allowed_updates":["message"]}}`))
+		case "/bot1234:secret/setWebhook":
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			vs, err := url.ParseQuery(string(body))
+			assert.NoError(t, err)
+
+			allowedUpdates = vs.Get("allowed_updates")
+
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Fatalf("unexcepted call '%s'", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	router := NewRouter().
+		Message(func(ctx context.Context, mu *MessageUpdate) error { return nil }).
+		MessageReaction(func(ctx context.Context, mru *MessageReactionUpdate) error { return nil })
+
+	webhook := NewWebhook(
+		router,
+		tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+		"https://google.com",
+	)
+
+	err := webhook.Setup(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, `["message","message_reaction"]`, allowedUpdates)
+}
+
 type loggerMock struct {
 	mock.Mock
 }
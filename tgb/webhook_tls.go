@@ -0,0 +1,155 @@
+package tgb
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// webhookAllowedPorts is the list of ports supported by Telegram for webhooks.
+var webhookAllowedPorts = []string{"443", "80", "88", "8443"}
+
+// WithWebhookSelfSignedCertificate enables TLS with a self-signed certificate generated for the host.
+// Host is the IP address or domain name of the webhook, as it specified in URL.
+//
+// Public key of the certificate is uploaded to Telegram during [Webhook.Setup],
+// and [Webhook.Run] serves TLS with it.
+// Certificate is generated once per Webhook, so it's uploaded again on each restart.
+func WithWebhookSelfSignedCertificate(host string) WebhookOption {
+	return func(webhook *Webhook) {
+		webhook.tlsSelfSignedHost = host
+	}
+}
+
+// WithWebhookCertificate enables TLS with certificate and private key loaded from PEM files.
+// Public key of the certificate is uploaded to Telegram during [Webhook.Setup],
+// so it can be self-signed, and [Webhook.Run] serves TLS with it.
+func WithWebhookCertificate(certFile, keyFile string) WebhookOption {
+	return func(webhook *Webhook) {
+		webhook.tlsCertFile = certFile
+		webhook.tlsKeyFile = keyFile
+	}
+}
+
+func (webhook *Webhook) isTLS() bool {
+	return webhook.tlsSelfSignedHost != "" || webhook.tlsCertFile != ""
+}
+
+// loadCertificate generates or loads certificate, if it's not loaded yet.
+func (webhook *Webhook) loadCertificate() error {
+	webhook.tlsLock.Lock()
+	defer webhook.tlsLock.Unlock()
+
+	if webhook.tlsCert != nil {
+		return nil
+	}
+
+	var (
+		certPEM, keyPEM []byte
+		err             error
+	)
+
+	if webhook.tlsCertFile != "" {
+		certPEM, err = os.ReadFile(webhook.tlsCertFile)
+		if err != nil {
+			return fmt.Errorf("read certificate: %w", err)
+		}
+
+		keyPEM, err = os.ReadFile(webhook.tlsKeyFile)
+		if err != nil {
+			return fmt.Errorf("read private key: %w", err)
+		}
+	} else {
+		certPEM, keyPEM, err = generateSelfSignedCertificate(webhook.tlsSelfSignedHost, time.Now())
+		if err != nil {
+			return fmt.Errorf("generate self-signed certificate: %w", err)
+		}
+
+		webhook.tlsCertUpload = true
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("parse certificate: %w", err)
+	}
+
+	webhook.tlsCert = &cert
+	webhook.tlsCertPEM = certPEM
+
+	return nil
+}
+
+// checkPort checks if port of the webhook URL is supported by Telegram.
+func (webhook *Webhook) checkPort() error {
+	u, err := url.Parse(webhook.url)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	if !slices.Contains(webhookAllowedPorts, port) {
+		return fmt.Errorf("port %s is not supported, use one of %v", port, webhookAllowedPorts)
+	}
+
+	return nil
+}
+
+// generateSelfSignedCertificate generates a PEM encoded certificate and RSA private key for host.
+func generateSelfSignedCertificate(host string, now time.Time) (certPEM []byte, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: host,
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return certPEM, keyPEM, nil
+}
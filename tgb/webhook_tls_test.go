@@ -0,0 +1,204 @@
+package tgb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tg "github.com/nosefu/go-tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSelfSignedCertificate(t *testing.T) {
+	parse := func(t *testing.T, certPEM []byte) *x509.Certificate {
+		t.Helper()
+
+		block, _ := pem.Decode(certPEM)
+		require.NotNil(t, block)
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+
+		return cert
+	}
+
+	t.Run("IP", func(t *testing.T) {
+		certPEM, keyPEM, err := generateSelfSignedCertificate("1.2.3.4", time.Now())
+		require.NoError(t, err)
+
+		_, err = tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+
+		cert := parse(t, certPEM)
+		assert.Equal(t, "1.2.3.4", cert.Subject.CommonName)
+		assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("1.2.3.4")))
+		assert.Empty(t, cert.DNSNames)
+	})
+
+	t.Run("Domain", func(t *testing.T) {
+		certPEM, _, err := generateSelfSignedCertificate("bot.example.com", time.Now())
+		require.NoError(t, err)
+
+		cert := parse(t, certPEM)
+		assert.Equal(t, []string{"bot.example.com"}, cert.DNSNames)
+		assert.NoError(t, cert.VerifyHostname("bot.example.com"))
+	})
+}
+
+func TestWebhook_SetupTLS(t *testing.T) {
+	newServer := func(t *testing.T, hasCustomCertificate bool, certificates *[][]byte) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			switch r.URL.Path {
+			case "/bot1234:secret/getWebhookInfo":
+				if hasCustomCertificate {
+					_, _ = w.Write([]byte(`{"ok":true,"result":{"url":"https://1.2.3.4:8443/webhook","has_custom_certificate":true,"max_connections":40}}`))
+				} else {
+					_, _ = w.Write([]byte(`{"ok":true,"result":{"url":"https://1.2.3.4:8443/webhook","max_connections":40}}`))
+				}
+			case "/bot1234:secret/setWebhook":
+				file, _, err := r.FormFile("certificate")
+				require.NoError(t, err)
+
+				body, err := io.ReadAll(file)
+				require.NoError(t, err)
+
+				*certificates = append(*certificates, body)
+
+				assert.Equal(t, "https://1.2.3.4:8443/webhook", r.FormValue("url"))
+
+				_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+			default:
+				t.Fatalf("unexcepted call '%s'", r.URL.Path)
+			}
+		}))
+	}
+
+	t.Run("SelfSigned", func(t *testing.T) {
+		var certificates [][]byte
+
+		server := newServer(t, true, &certificates)
+		defer server.Close()
+
+		webhook := NewWebhook(
+			HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+			tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+			"https://1.2.3.4:8443/webhook",
+			WithWebhookSelfSignedCertificate("1.2.3.4"),
+		)
+
+		// generated certificate is uploaded even if telegram already has one
+		require.NoError(t, webhook.Setup(context.Background()))
+		require.Len(t, certificates, 1)
+		assert.Equal(t, webhook.tlsCertPEM, certificates[0])
+
+		// but only once
+		require.NoError(t, webhook.Setup(context.Background()))
+		assert.Len(t, certificates, 1)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		var certificates [][]byte
+
+		server := newServer(t, false, &certificates)
+		defer server.Close()
+
+		dir := t.TempDir()
+
+		certPEM, keyPEM, err := generateSelfSignedCertificate("1.2.3.4", time.Now())
+		require.NoError(t, err)
+
+		certFile := filepath.Join(dir, "cert.pem")
+		keyFile := filepath.Join(dir, "key.pem")
+
+		require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+		require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+		webhook := NewWebhook(
+			HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+			tg.New("1234:secret", tg.WithClientServerURL(server.URL), tg.WithClientDoer(server.Client())),
+			"https://1.2.3.4:8443/webhook",
+			WithWebhookCertificate(certFile, keyFile),
+		)
+
+		require.NoError(t, webhook.Setup(context.Background()))
+		require.Len(t, certificates, 1)
+		assert.Equal(t, certPEM, certificates[0])
+	})
+
+	t.Run("FileNotFound", func(t *testing.T) {
+		webhook := NewWebhook(
+			HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+			&tg.Client{},
+			"https://1.2.3.4/webhook",
+			WithWebhookCertificate("not-found.pem", "not-found.key"),
+		)
+
+		assert.Error(t, webhook.Setup(context.Background()))
+	})
+
+	t.Run("UnsupportedPort", func(t *testing.T) {
+		webhook := NewWebhook(
+			HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+			&tg.Client{},
+			"https://1.2.3.4:8080/webhook",
+			WithWebhookSelfSignedCertificate("1.2.3.4"),
+		)
+
+		err := webhook.Setup(context.Background())
+		assert.ErrorContains(t, err, "port 8080 is not supported")
+	})
+}
+
+func TestWebhook_RunTLS(t *testing.T) {
+	webhook := NewWebhook(
+		HandlerFunc(func(ctx context.Context, update *Update) error { return nil }),
+		&tg.Client{},
+		"https://127.0.0.1:8443/webhook",
+		WithWebhookSelfSignedCertificate("127.0.0.1"),
+	)
+	webhook.isSetup = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		err := webhook.Run(ctx, "127.0.0.1:12346")
+		assert.NoError(t, err)
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+
+	pool := x509.NewCertPool()
+	require.NoError(t, webhook.loadCertificate())
+	require.True(t, pool.AppendCertsFromPEM(webhook.tlsCertPEM))
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	res, err := client.Get("https://127.0.0.1:12346/webhook")
+	require.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	cancel()
+	<-done
+}
@@ -1153,7 +1153,7 @@ func (typ *UpdateType) UnmarshalText(v []byte) error {
 
 // String returns string representation of UpdateType.
 func (typ UpdateType) String() string {
-	if typ > UpdateTypeUnknown && typ <= UpdateTypeRemovedChatBoost {
+	if typ > UpdateTypeUnknown && typ <= UpdateTypeDeletedBusinessMessages {
 		return [...]string{
 			"message",
 			"edited_message",
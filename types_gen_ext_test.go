@@ -797,6 +797,11 @@ func TestUpdateType_String(t *testing.T) {
 		{UpdateTypeMessageReactionCount, "message_reaction_count"},
 		{UpdateTypeChatBoost, "chat_boost"},
 		{UpdateTypeRemovedChatBoost, "removed_chat_boost"},
+		{UpdateTypeBusinessConnection, "business_connection"},
+		{UpdateTypeBusinessMessage, "business_message"},
+		{UpdateTypeEditedBusinessMessage, "edited_business_message"},
+		{UpdateTypeDeletedBusinessMessages, "deleted_business_messages"},
+		{UpdateTypeDeletedBusinessMessages + 1, "unknown"},
 	} {
 		assert.Equal(t, test.Want, test.Type.String(), "update type: %s", test.Want)
 	}